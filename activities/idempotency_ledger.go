@@ -0,0 +1,116 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// IdempotencyLedger records the result of every completed side effect by idempotency key
+type IdempotencyLedger interface {
+	// Lookup returns the recorded result for key, if any
+	Lookup(key string) (string, bool)
+	// Record stores the result for key
+	Record(key, result string)
+}
+
+// Limits of the ledger created by NewMemoryLedger
+const (
+	DefaultLedgerTTL        = 24 * time.Hour
+	DefaultLedgerMaxEntries = 100000
+)
+
+// MemoryLedger is an in-process IdempotencyLedger. Results expire after a TTL and the oldest
+// results are evicted once the ledger holds its maximum number of entries.
+type MemoryLedger struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	results    map[string]ledgerEntry
+	// order lists the keys by the time they were recorded, oldest first
+	order []ledgerKey
+}
+
+// ledgerEntry is a recorded result
+type ledgerEntry struct {
+	result     string
+	recordedAt time.Time
+}
+
+// ledgerKey is an entry of the eviction order of a MemoryLedger
+type ledgerKey struct {
+	key        string
+	recordedAt time.Time
+}
+
+// NewMemoryLedger creates an empty MemoryLedger with DefaultLedgerTTL and DefaultLedgerMaxEntries
+func NewMemoryLedger() *MemoryLedger {
+	return NewMemoryLedgerWithLimits(DefaultLedgerTTL, DefaultLedgerMaxEntries)
+}
+
+// NewMemoryLedgerWithLimits creates an empty MemoryLedger keeping results for ttl and at most
+// maxEntries results
+func NewMemoryLedgerWithLimits(ttl time.Duration, maxEntries int) *MemoryLedger {
+	return &MemoryLedger{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		results:    make(map[string]ledgerEntry),
+	}
+}
+
+// Lookup returns the recorded result for key, if any
+func (l *MemoryLedger) Lookup(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.results[key]
+	if !ok || time.Since(entry.recordedAt) > l.ttl {
+		return "", false
+	}
+	return entry.result, true
+}
+
+// Record stores the result for key, evicting expired and, above the size limit, the oldest results
+func (l *MemoryLedger) Record(key, result string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.results[key] = ledgerEntry{result: result, recordedAt: now}
+	l.order = append(l.order, ledgerKey{key: key, recordedAt: now})
+
+	for len(l.order) > 0 {
+		oldest := l.order[0]
+		entry, ok := l.results[oldest.key]
+		switch {
+		case !ok || !entry.recordedAt.Equal(oldest.recordedAt):
+			// The key was recorded again later, a newer element of order refers to it
+		case now.Sub(oldest.recordedAt) > l.ttl || len(l.results) > l.maxEntries:
+			delete(l.results, oldest.key)
+		default:
+			return
+		}
+		l.order = l.order[1:]
+	}
+}
+
+// Len returns the number of results held, including expired ones not yet evicted
+func (l *MemoryLedger) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.results)
+}
+
+// IdempotencyKey derives a key that is identical for every attempt of the current activity.
+// The activity ID is stable across retries, so together with the workflow and run ID and the
+// business reference (order, authorization or transaction ID) it identifies one side effect.
+// The run ID keeps an order resubmitted under the same workflow ID from reusing the results
+// of the earlier run.
+func IdempotencyKey(ctx context.Context, reference string) string {
+	info := activity.GetInfo(ctx)
+	return fmt.Sprintf("%s/%s/%s/%s", info.WorkflowExecution.ID, info.WorkflowExecution.RunID, info.ActivityID, reference)
+}
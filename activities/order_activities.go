@@ -9,11 +9,19 @@ import (
 	"net/http"
 	"time"
 
+	"temporal-order-system/metrics"
 	"temporal-order-system/models"
+	"temporal-order-system/tracing"
 
+	"go.opentelemetry.io/otel/propagation"
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 )
 
+// ErrTypeValidationFailed is the application error type of orders the validation service
+// rejected; a rejection is not transient, so it is not retried
+const ErrTypeValidationFailed = "ValidationFailed"
+
 // Activities contains all order processing activities
 type Activities struct {
 	httpClient        *http.Client
@@ -33,7 +41,7 @@ func NewActivities(validationBaseURL string) *Activities {
 // ValidateOrder validates an order by calling an external validation service
 func (a *Activities) ValidateOrder(ctx context.Context, order models.Order) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Validating order", "order_id", order.ID, "amount", order.Amount)
+	logger.Info("Validating order", "amount", order.Amount)
 
 	// Create validation request
 	validationReq := models.ValidationRequest{
@@ -53,6 +61,8 @@ func (a *Activities) ValidateOrder(ctx context.Context, order models.Order) erro
 		return fmt.Errorf("failed to create validation request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	// Continue the trace of the activity in the validation service
+	tracing.Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// Heartbeat to let Temporal know we're still alive
 	activity.RecordHeartbeat(ctx, "calling validation service")
@@ -77,17 +87,18 @@ func (a *Activities) ValidateOrder(ctx context.Context, order models.Order) erro
 	activity.RecordHeartbeat(ctx, "validation response received")
 
 	if !validationResp.Valid {
-		return fmt.Errorf("order validation failed: %s", validationResp.Message)
+		activity.GetMetricsHandler(ctx).Counter(metrics.ValidationRejections).Inc(1)
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("order validation failed: %s", validationResp.Message), ErrTypeValidationFailed, nil)
 	}
 
-	logger.Info("Order validated successfully", "order_id", order.ID, "message", validationResp.Message)
+	logger.Info("Order validated successfully", "message", validationResp.Message)
 	return nil
 }
 
 // ProcessOrder simulates order processing with business logic
 func (a *Activities) ProcessOrder(ctx context.Context, order models.Order) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Processing order", "order_id", order.ID)
+	logger.Info("Processing order")
 
 	// Simulate processing time with context-aware wait
 	select {
@@ -101,16 +112,16 @@ func (a *Activities) ProcessOrder(ctx context.Context, order models.Order) error
 	activity.RecordHeartbeat(ctx, "order processing in progress")
 
 	// Simulate business logic
-	logger.Info("Applying business rules", "order_id", order.ID)
+	logger.Info("Applying business rules")
 
 	// Calculate total and verify
-	var calculatedTotal float64
-	for _, item := range order.Items {
-		calculatedTotal += item.Price * float64(item.Quantity)
+	calculatedTotal, err := order.Total()
+	if err != nil {
+		return fmt.Errorf("failed to calculate order total: %w", err)
 	}
 
-	if calculatedTotal != order.Amount {
-		return fmt.Errorf("order amount mismatch: expected %.2f, got %.2f", calculatedTotal, order.Amount)
+	if !calculatedTotal.Equal(order.Amount) {
+		return fmt.Errorf("order amount mismatch: expected %s, got %s", calculatedTotal, order.Amount)
 	}
 
 	// Simulate inventory check with context-aware wait
@@ -123,14 +134,14 @@ func (a *Activities) ProcessOrder(ctx context.Context, order models.Order) error
 
 	activity.RecordHeartbeat(ctx, "inventory checked")
 
-	logger.Info("Order processed successfully", "order_id", order.ID)
+	logger.Info("Order processed successfully")
 	return nil
 }
 
 // NotifyCustomer sends a notification to the customer
 func (a *Activities) NotifyCustomer(ctx context.Context, order models.Order, message string) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Notifying customer", "order_id", order.ID, "message", message)
+	logger.Info("Notifying customer", "message", message)
 
 	// Simulate notification delay with context-aware wait
 	select {
@@ -140,14 +151,14 @@ func (a *Activities) NotifyCustomer(ctx context.Context, order models.Order, mes
 		return ctx.Err()
 	}
 
-	logger.Info("Customer notified successfully", "order_id", order.ID)
+	logger.Info("Customer notified successfully")
 	return nil
 }
 
 // RollbackOrder rolls back order processing in case of failure
 func (a *Activities) RollbackOrder(ctx context.Context, order models.Order) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Rolling back order", "order_id", order.ID)
+	logger.Info("Rolling back order")
 
 	// Simulate rollback operations with context-aware wait
 	select {
@@ -157,6 +168,6 @@ func (a *Activities) RollbackOrder(ctx context.Context, order models.Order) erro
 		return ctx.Err()
 	}
 
-	logger.Info("Order rolled back successfully", "order_id", order.ID)
+	logger.Info("Order rolled back successfully")
 	return nil
 }
@@ -2,80 +2,106 @@ package activities
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"temporal-order-system/gateway"
+	"temporal-order-system/metrics"
 	"temporal-order-system/models"
 
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Application error types of payment operations the gateway rejected; they are not retried
+const (
+	ErrTypePaymentDeclined = "PaymentDeclined"
+	ErrTypePaymentNotFound = "PaymentNotFound"
 )
 
 // PaymentActivities contains all payment-related activities
-type PaymentActivities struct{}
+type PaymentActivities struct {
+	gateway gateway.PaymentGateway
+	ledger  IdempotencyLedger
+}
 
-// NewPaymentActivities creates a new PaymentActivities instance
-func NewPaymentActivities() *PaymentActivities {
-	return &PaymentActivities{}
+// NewPaymentActivities creates a new PaymentActivities instance backed by the given gateway
+func NewPaymentActivities(gw gateway.PaymentGateway) *PaymentActivities {
+	return NewPaymentActivitiesWithLedger(gw, NewMemoryLedger())
+}
+
+// NewPaymentActivitiesWithLedger creates a new PaymentActivities instance that records
+// completed payment operations in the given ledger
+func NewPaymentActivitiesWithLedger(gw gateway.PaymentGateway, ledger IdempotencyLedger) *PaymentActivities {
+	return &PaymentActivities{
+		gateway: gw,
+		ledger:  ledger,
+	}
 }
 
 // AuthorizePayment authorizes a payment for the given order
 func (p *PaymentActivities) AuthorizePayment(ctx context.Context, order models.Order) (string, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Authorizing payment", "order_id", order.ID, "amount", order.Amount)
-
-	// Simulate payment authorization processing with context-aware wait
-	select {
-	case <-time.After(1 * time.Second):
-		// Processing complete
-	case <-ctx.Done():
-		return "", ctx.Err()
+	logger.Info("Authorizing payment", "amount", order.Amount)
+
+	key := IdempotencyKey(ctx, order.ID)
+	if authorizationID, ok := p.ledger.Lookup(key); ok {
+		logger.Info("Payment already authorized", "authorization_id", authorizationID)
+		return authorizationID, nil
 	}
 
 	activity.RecordHeartbeat(ctx, "authorizing payment")
 
-	// Simulate payment validation
-	if order.Amount <= 0 {
-		return "", fmt.Errorf("invalid payment amount: %.2f", order.Amount)
+	start := time.Now()
+	auth, err := p.gateway.Authorize(ctx, gateway.AuthorizeRequest{
+		IdempotencyKey: key,
+		OrderID:        order.ID,
+		Amount:         order.Amount,
+	})
+	recordLatency(ctx, metrics.PaymentAuthorizeLatency, start, err)
+	if err != nil {
+		return "", gatewayError("payment authorization failed", err)
 	}
+	p.ledger.Record(key, auth.ID)
 
-	if order.Amount > 9999 {
-		return "", fmt.Errorf("payment amount exceeds authorization limit")
-	}
-
-	// Generate deterministic authorization ID based on activity info
-	info := activity.GetInfo(ctx)
-	authorizationID := fmt.Sprintf("AUTH-%s-%d", order.ID[:8], info.Attempt)
-
-	logger.Info("Payment authorized successfully", "order_id", order.ID, "authorization_id", authorizationID)
-	return authorizationID, nil
+	logger.Info("Payment authorized successfully", "authorization_id", auth.ID)
+	return auth.ID, nil
 }
 
 // CapturePayment captures a previously authorized payment
 func (p *PaymentActivities) CapturePayment(ctx context.Context, order models.Order, authorizationID string) (string, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Capturing payment", "order_id", order.ID, "authorization_id", authorizationID)
-
-	// Simulate payment capture processing with context-aware wait
-	select {
-	case <-time.After(1500 * time.Millisecond):
-		// Processing complete
-	case <-ctx.Done():
-		return "", ctx.Err()
-	}
-
-	activity.RecordHeartbeat(ctx, "capturing payment")
+	logger.Info("Capturing payment", "authorization_id", authorizationID)
 
 	// Validate authorization ID
 	if authorizationID == "" {
 		return "", fmt.Errorf("invalid authorization ID")
 	}
 
-	// Generate deterministic transaction ID based on activity info
-	info := activity.GetInfo(ctx)
-	transactionID := fmt.Sprintf("TXN-%s-%d", order.ID[:8], info.Attempt)
+	key := IdempotencyKey(ctx, order.ID)
+	if transactionID, ok := p.ledger.Lookup(key); ok {
+		logger.Info("Payment already captured", "transaction_id", transactionID)
+		return transactionID, nil
+	}
+
+	activity.RecordHeartbeat(ctx, "capturing payment")
 
-	logger.Info("Payment captured successfully", "order_id", order.ID, "transaction_id", transactionID)
-	return transactionID, nil
+	start := time.Now()
+	txn, err := p.gateway.Capture(ctx, gateway.CaptureRequest{
+		IdempotencyKey:  key,
+		OrderID:         order.ID,
+		AuthorizationID: authorizationID,
+		Amount:          order.Amount,
+	})
+	recordLatency(ctx, metrics.PaymentCaptureLatency, start, err)
+	if err != nil {
+		return "", gatewayError("payment capture failed", err)
+	}
+	p.ledger.Record(key, txn.ID)
+
+	logger.Info("Payment captured successfully", "transaction_id", txn.ID)
+	return txn.ID, nil
 }
 
 // VoidAuthorization voids a payment authorization
@@ -83,42 +109,70 @@ func (p *PaymentActivities) VoidAuthorization(ctx context.Context, authorization
 	logger := activity.GetLogger(ctx)
 	logger.Info("Voiding authorization", "authorization_id", authorizationID)
 
-	// Simulate void processing with context-aware wait
-	select {
-	case <-time.After(500 * time.Millisecond):
-		// Processing complete
-	case <-ctx.Done():
-		return ctx.Err()
+	key := IdempotencyKey(ctx, authorizationID)
+	if _, ok := p.ledger.Lookup(key); ok {
+		logger.Info("Authorization already voided", "authorization_id", authorizationID)
+		return nil
+	}
+
+	err := p.gateway.Void(ctx, gateway.VoidRequest{
+		IdempotencyKey:  key,
+		AuthorizationID: authorizationID,
+	})
+	if err != nil {
+		return gatewayError("authorization void failed", err)
 	}
+	p.ledger.Record(key, authorizationID)
 
 	logger.Info("Authorization voided successfully", "authorization_id", authorizationID)
 	return nil
 }
 
 // RefundPayment refunds a captured payment
-func (p *PaymentActivities) RefundPayment(ctx context.Context, transactionID string, amount float64) (string, error) {
+func (p *PaymentActivities) RefundPayment(ctx context.Context, transactionID string, amount models.Money) (string, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Refunding payment", "transaction_id", transactionID, "amount", amount)
 
-	// Simulate refund processing with context-aware wait
-	select {
-	case <-time.After(1 * time.Second):
-		// Processing complete
-	case <-ctx.Done():
-		return "", ctx.Err()
+	key := IdempotencyKey(ctx, transactionID)
+	if refundID, ok := p.ledger.Lookup(key); ok {
+		logger.Info("Payment already refunded", "transaction_id", transactionID, "refund_id", refundID)
+		return refundID, nil
 	}
 
 	activity.RecordHeartbeat(ctx, "processing refund")
 
-	// Generate deterministic refund ID based on activity info
-	info := activity.GetInfo(ctx)
-	// Use a substring of transaction ID safely (minimum of 8 chars or full length)
-	txnIDPart := transactionID
-	if len(transactionID) > 12 {
-		txnIDPart = transactionID[4:12] // Skip "TXN-" prefix and take next 8 chars
+	refund, err := p.gateway.Refund(ctx, gateway.RefundRequest{
+		IdempotencyKey: key,
+		TransactionID:  transactionID,
+		Amount:         amount,
+	})
+	if err != nil {
+		return "", gatewayError("refund failed", err)
 	}
-	refundID := fmt.Sprintf("REFUND-%s-%d", txnIDPart, info.Attempt)
+	p.ledger.Record(key, refund.ID)
 
-	logger.Info("Refund processed successfully", "transaction_id", transactionID, "refund_id", refundID)
-	return refundID, nil
+	logger.Info("Refund processed successfully", "transaction_id", transactionID, "refund_id", refund.ID)
+	return refund.ID, nil
+}
+
+// gatewayError annotates an error of the gateway with message. Declined and unknown payments
+// fail the activity without retries; other errors, such as transport failures, are retried.
+func gatewayError(message string, err error) error {
+	switch {
+	case errors.Is(err, gateway.ErrDeclined):
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("%s: %v", message, err), ErrTypePaymentDeclined, err)
+	case errors.Is(err, gateway.ErrNotFound):
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("%s: %v", message, err), ErrTypePaymentNotFound, err)
+	default:
+		return fmt.Errorf("%s: %w", message, err)
+	}
+}
+
+// recordLatency records the time a gateway call took since start, tagged with its outcome
+func recordLatency(ctx context.Context, name string, start time.Time, err error) {
+	outcome := metrics.OutcomeSuccess
+	if err != nil {
+		outcome = metrics.OutcomeFailure
+	}
+	activity.GetMetricsHandler(ctx).WithTags(map[string]string{metrics.TagOutcome: outcome}).Timer(name).Record(time.Since(start))
 }
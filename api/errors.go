@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.temporal.io/api/serviceerror"
+)
+
+// Error is the body of every error response
+type Error struct {
+	// Code is a stable, machine-readable name of the error, e.g. "order_exists"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse wraps an Error as {"error": {...}}
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+// requestError is an error in the request itself, answered with 400 Bad Request
+type requestError struct {
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// temporalError maps an error of the Temporal client to an HTTP status and error code
+func temporalError(err error) (int, string) {
+	var request *requestError
+	var invalidArgument *serviceerror.InvalidArgument
+	var notFound *serviceerror.NotFound
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	var failedPrecondition *serviceerror.FailedPrecondition
+	var permissionDenied *serviceerror.PermissionDenied
+	var resourceExhausted *serviceerror.ResourceExhausted
+	var unavailable *serviceerror.Unavailable
+	var deadline *serviceerror.DeadlineExceeded
+
+	switch {
+	case errors.As(err, &request):
+		return http.StatusBadRequest, request.code
+	case errors.As(err, &invalidArgument):
+		return http.StatusBadRequest, "invalid_argument"
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, "not_found"
+	case errors.As(err, &alreadyStarted):
+		return http.StatusConflict, "order_exists"
+	case errors.As(err, &failedPrecondition):
+		return http.StatusConflict, "failed_precondition"
+	case errors.As(err, &permissionDenied):
+		return http.StatusForbidden, "permission_denied"
+	case errors.As(err, &resourceExhausted):
+		return http.StatusTooManyRequests, "resource_exhausted"
+	case errors.As(err, &unavailable):
+		return http.StatusServiceUnavailable, "unavailable"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &deadline):
+		return http.StatusGatewayTimeout, "timeout"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
@@ -0,0 +1,405 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	// DefaultRequestTimeout bounds the Temporal calls of a request
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultPageSize is the number of orders GET /orders returns without page_size
+	DefaultPageSize = 20
+	// MaxPageSize is the largest page_size GET /orders accepts
+	MaxPageSize = 100
+	// DefaultMaxWatchersPerOrder is the number of event streams an order may have at a time
+	DefaultMaxWatchersPerOrder = 2
+
+	// maxBodyBytes caps the size of an order in POST /orders
+	maxBodyBytes = 1 << 20
+)
+
+// openAPISpec documents the endpoints of the Server; it is served at /openapi.yaml
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// listStatuses are the execution statuses GET /orders can filter by
+var listStatuses = []string{"Running", "Completed", "Failed", "Canceled", "Terminated", "ContinuedAsNew", "TimedOut"}
+
+// Options configures a Server
+type Options struct {
+	// TaskQueue is the task queue of the order workers
+	TaskQueue string
+	// RequestTimeout bounds the Temporal calls of a request; DefaultRequestTimeout when zero
+	RequestTimeout time.Duration
+	// MaxWatchersPerOrder bounds the event streams of an order, each of which sends
+	// wait-for-change updates to its workflow; DefaultMaxWatchersPerOrder when zero
+	MaxWatchersPerOrder int
+}
+
+// OrderCreated is the response of POST /orders
+type OrderCreated struct {
+	OrderID    string       `json:"order_id"`
+	WorkflowID string       `json:"workflow_id"`
+	RunID      string       `json:"run_id"`
+	Amount     models.Money `json:"amount"`
+}
+
+// SignalSent is the response of the cancel and expedite endpoints
+type SignalSent struct {
+	OrderID    string `json:"order_id"`
+	WorkflowID string `json:"workflow_id"`
+	Signal     string `json:"signal"`
+}
+
+// OrderSummary is an order in the response of GET /orders
+type OrderSummary struct {
+	OrderID    string     `json:"order_id"`
+	WorkflowID string     `json:"workflow_id"`
+	RunID      string     `json:"run_id"`
+	Status     string     `json:"status"`
+	StartTime  time.Time  `json:"start_time"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+}
+
+// OrderList is the response of GET /orders
+type OrderList struct {
+	Orders []OrderSummary `json:"orders"`
+	// NextPageToken is passed as next_page_token to get the next page; empty on the last page
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// Server serves the REST API for submitting and managing orders, backed by the order workflows
+type Server struct {
+	client         client.Client
+	taskQueue      string
+	requestTimeout time.Duration
+	mux            *http.ServeMux
+
+	maxWatchers int
+	watchersMu  sync.Mutex
+	watchers    map[string]int
+}
+
+// NewServer creates a Server that starts and reaches order workflows through c
+func NewServer(c client.Client, opts Options) *Server {
+	s := &Server{
+		client:         c,
+		taskQueue:      opts.TaskQueue,
+		requestTimeout: opts.RequestTimeout,
+		mux:            http.NewServeMux(),
+		maxWatchers:    opts.MaxWatchersPerOrder,
+		watchers:       map[string]int{},
+	}
+	if s.requestTimeout <= 0 {
+		s.requestTimeout = DefaultRequestTimeout
+	}
+	if s.maxWatchers <= 0 {
+		s.maxWatchers = DefaultMaxWatchersPerOrder
+	}
+
+	s.mux.HandleFunc("POST /orders", s.createOrder)
+	s.mux.HandleFunc("GET /orders", s.listOrders)
+	s.mux.HandleFunc("GET /orders/{id}", s.getOrder)
+	s.mux.HandleFunc("GET /orders/{id}/events", s.streamOrder)
+	s.mux.HandleFunc("POST /orders/{id}/cancel", s.signalOrder(workflows.SignalCancel))
+	s.mux.HandleFunc("POST /orders/{id}/expedite", s.signalOrder(workflows.SignalExpedite))
+	s.mux.HandleFunc("GET /openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+	})
+	return s
+}
+
+// ServeHTTP routes the request to its endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// createOrder validates the order in the body and starts its workflow. The amount is computed
+// from the items and discount code; an order whose amount differs from it is a bad request.
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	var order models.Order
+	if err := decoder.Decode(&order); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: Error{Code: "too_large", Message: err.Error()}})
+			return
+		}
+		writeError(w, &requestError{code: "invalid_request", message: fmt.Sprintf("invalid order JSON: %v", err)})
+		return
+	}
+	if err := order.Prepare(time.Now()); err != nil {
+		writeError(w, &requestError{code: "invalid_order", message: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	run, err := s.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        workflows.OrderWorkflowID(order.ID),
+		TaskQueue: s.taskQueue,
+		// A running order with the same ID is a conflict, not a handle to the existing run
+		WorkflowExecutionErrorWhenAlreadyStarted: true,
+	}, workflows.OrderWorkflow, order)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to start order %s: %w", order.ID, err))
+		return
+	}
+
+	w.Header().Set("Location", "/orders/"+order.ID)
+	writeJSON(w, http.StatusCreated, OrderCreated{
+		OrderID:    order.ID,
+		WorkflowID: run.GetID(),
+		RunID:      run.GetRunID(),
+		Amount:     order.Amount,
+	})
+}
+
+// getOrder returns the state of an order from the state query of its workflow
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	resp, err := s.client.QueryWorkflow(ctx, workflows.OrderWorkflowID(orderID), "", workflows.QueryState)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to query order %s: %w", orderID, err))
+		return
+	}
+
+	var state models.WorkflowState
+	if err := resp.Get(&state); err != nil {
+		writeError(w, fmt.Errorf("failed to decode state of order %s: %w", orderID, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// streamOrder streams the progress of an order as server-sent events: a "progress" event with
+// the state and the timeline whenever they change, then an "end" event once the workflow has
+// closed. Event IDs are state versions, so a reconnecting client resumes after Last-Event-ID.
+// Every stream loops on the wait-for-change update, which adds to the history of the workflow,
+// so an order has at most maxWatchers streams.
+func (s *Server) streamOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("id")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.New("response writer does not support streaming"))
+		return
+	}
+
+	version := int64(-1)
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		seen, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, &requestError{code: "invalid_request", message: "Last-Event-ID must be a state version"})
+			return
+		}
+		version = seen
+	}
+
+	if !s.addWatcher(orderID) {
+		writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: Error{
+			Code:    "too_many_watchers",
+			Message: fmt.Sprintf("order %s already has %d event streams", orderID, s.maxWatchers),
+		}})
+		return
+	}
+	defer s.removeWatcher(orderID)
+
+	// Errors before the first event still get a status code; later ones become an error event
+	streaming := false
+	err := WatchOrder(r.Context(), s.client, workflows.OrderWorkflowID(orderID), "", version, func(progress models.OrderProgress) error {
+		if !streaming {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			streaming = true
+		}
+		if err := writeEvent(w, "progress", strconv.FormatInt(progress.State.Version, 10), progress); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+
+	switch {
+	case r.Context().Err() != nil:
+		// The client went away
+	case err != nil && !streaming:
+		writeError(w, fmt.Errorf("failed to watch order %s: %w", orderID, err))
+	case err != nil:
+		status, code := temporalError(err)
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			slog.Error("API stream failed", "error", err)
+			message = "internal server error"
+		}
+		writeEvent(w, "error", "", Error{Code: code, Message: message})
+		flusher.Flush()
+	default:
+		writeEvent(w, "end", "", struct{}{})
+		flusher.Flush()
+	}
+}
+
+// addWatcher counts a new event stream of an order, unless it already has maxWatchers
+func (s *Server) addWatcher(orderID string) bool {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+	if s.watchers[orderID] >= s.maxWatchers {
+		return false
+	}
+	s.watchers[orderID]++
+	return true
+}
+
+// removeWatcher uncounts an event stream of an order once it has ended
+func (s *Server) removeWatcher(orderID string) {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+	if s.watchers[orderID]--; s.watchers[orderID] <= 0 {
+		delete(s.watchers, orderID)
+	}
+}
+
+// writeEvent writes a server-sent event with v as its JSON data
+func writeEvent(w http.ResponseWriter, event, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// signalOrder returns the handler sending signal to the workflow of an order. The workflow
+// decides whether the signal still applies, see workflows.OrderWorkflow.
+func (s *Server) signalOrder(signal string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID := r.PathValue("id")
+		workflowID := workflows.OrderWorkflowID(orderID)
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+		defer cancel()
+
+		if err := s.client.SignalWorkflow(ctx, workflowID, "", signal, signal); err != nil {
+			writeError(w, fmt.Errorf("failed to %s order %s: %w", signal, orderID, err))
+			return
+		}
+		writeJSON(w, http.StatusAccepted, SignalSent{OrderID: orderID, WorkflowID: workflowID, Signal: signal})
+	}
+}
+
+// listOrders lists order workflows, newest first, through the visibility store
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	pageSize := DefaultPageSize
+	if value := params.Get("page_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 || size > MaxPageSize {
+			writeError(w, &requestError{code: "invalid_request", message: fmt.Sprintf("page_size must be between 1 and %d", MaxPageSize)})
+			return
+		}
+		pageSize = size
+	}
+
+	query := "WorkflowType = 'OrderWorkflow'"
+	if status := params.Get("status"); status != "" {
+		// Only known statuses reach the visibility query
+		if !slices.Contains(listStatuses, status) {
+			writeError(w, &requestError{code: "invalid_request", message: fmt.Sprintf("status must be one of %s", strings.Join(listStatuses, ", "))})
+			return
+		}
+		query += fmt.Sprintf(" AND ExecutionStatus = '%s'", status)
+	}
+
+	pageToken, err := base64.RawURLEncoding.DecodeString(params.Get("next_page_token"))
+	if err != nil {
+		writeError(w, &requestError{code: "invalid_request", message: "invalid next_page_token"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	resp, err := s.client.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		PageSize:      int32(pageSize),
+		NextPageToken: pageToken,
+		Query:         query,
+	})
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to list orders: %w", err))
+		return
+	}
+
+	list := OrderList{
+		Orders:        []OrderSummary{},
+		NextPageToken: base64.RawURLEncoding.EncodeToString(resp.GetNextPageToken()),
+	}
+	for _, info := range resp.GetExecutions() {
+		summary := OrderSummary{
+			OrderID:    strings.TrimPrefix(info.GetExecution().GetWorkflowId(), workflows.OrderWorkflowID("")),
+			WorkflowID: info.GetExecution().GetWorkflowId(),
+			RunID:      info.GetExecution().GetRunId(),
+			Status:     info.GetStatus().String(),
+			StartTime:  info.GetStartTime().AsTime(),
+		}
+		if info.GetCloseTime() != nil {
+			closeTime := info.GetCloseTime().AsTime()
+			summary.CloseTime = &closeTime
+		}
+		list.Orders = append(list.Orders, summary)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// writeError writes the error response of err. Messages of unexpected errors are logged
+// instead of returned, since they may describe the internals of the server.
+func writeError(w http.ResponseWriter, err error) {
+	status, code := temporalError(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		slog.Error("API request failed", "error", err)
+		message = "internal server error"
+	}
+	writeJSON(w, status, errorResponse{Error: Error{Code: code, Message: message}})
+}
+
+// writeJSON writes v as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write API response", "error", err)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// WatchOrder follows the progress of an order workflow run, the latest without runID, with the
+// wait-for-change update and calls onChange with every new version, starting after version
+// (-1 for the current progress). It returns nil once the workflow has closed; the last
+// progress passed to onChange is then final.
+func WatchOrder(ctx context.Context, c client.Client, workflowID, runID string, version int64, onChange func(models.OrderProgress) error) error {
+	for {
+		handle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowID,
+			RunID:        runID,
+			UpdateName:   workflows.UpdateWaitForChange,
+			Args:         []interface{}{workflows.ChangeWait{Version: version}},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		var progress models.OrderProgress
+		if err == nil {
+			err = handle.Get(ctx, &progress)
+		}
+		if err != nil {
+			// The workflow may have closed between two updates
+			if closed, describeErr := workflowClosed(ctx, c, workflowID, runID); describeErr == nil && closed {
+				return finalProgress(ctx, c, workflowID, runID, onChange)
+			}
+			return fmt.Errorf("failed to wait for order progress: %w", err)
+		}
+
+		if progress.State.Version != version || progress.Final {
+			if err := onChange(progress); err != nil {
+				return err
+			}
+			version = progress.State.Version
+		}
+		if progress.Final {
+			return nil
+		}
+	}
+}
+
+// workflowClosed reports whether a workflow run has closed
+func workflowClosed(ctx context.Context, c client.Client, workflowID, runID string) (bool, error) {
+	resp, err := c.DescribeWorkflowExecution(ctx, workflowID, runID)
+	if err != nil {
+		return false, err
+	}
+	return resp.GetWorkflowExecutionInfo().GetStatus() != enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING, nil
+}
+
+// finalProgress passes the progress of a closed workflow to onChange, read with queries since
+// closed workflows take no updates
+func finalProgress(ctx context.Context, c client.Client, workflowID, runID string, onChange func(models.OrderProgress) error) error {
+	progress := models.OrderProgress{Final: true}
+
+	resp, err := c.QueryWorkflow(ctx, workflowID, runID, workflows.QueryState)
+	if err != nil {
+		return fmt.Errorf("failed to query order state: %w", err)
+	}
+	if err := resp.Get(&progress.State); err != nil {
+		return fmt.Errorf("failed to decode order state: %w", err)
+	}
+
+	resp, err = c.QueryWorkflow(ctx, workflowID, runID, workflows.QueryTimeline)
+	if err != nil {
+		return fmt.Errorf("failed to query order timeline: %w", err)
+	}
+	if err := resp.Get(&progress.Timeline); err != nil {
+		return fmt.Errorf("failed to decode order timeline: %w", err)
+	}
+	return onChange(progress)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/gateway"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	TaskQueueName = "history-capture-queue"
+
+	// Generation names the directory of the captured histories after the newest
+	// workflow.GetVersion change of the workflows. Bump it with every new change, and list it
+	// in historyGenerations of tests/replay_test.go, so the histories of older code stay in
+	// the corpus.
+	Generation = "refund-captured-payments"
+)
+
+// scenario is an order workflow run whose history is kept in the replay corpus
+type scenario struct {
+	name string
+	// startSignal is sent with the start request
+	startSignal string
+	// signal is sent once the workflow has been running for signalAfter
+	signal      string
+	signalAfter time.Duration
+	// child also captures the history of the payment child workflow
+	child bool
+}
+
+var scenarios = []scenario{
+	{name: "completed", child: true},
+	{name: "processing-failed", child: true},
+	{name: "capture-failed", child: true},
+	{name: "cancelled", signal: workflows.SignalCancel, signalAfter: 300 * time.Millisecond},
+	{name: "cancelled-during-payment", signal: workflows.SignalCancel, signalAfter: 1300 * time.Millisecond, child: true},
+	{name: "expedited", startSignal: workflows.SignalExpedite},
+}
+
+func main() {
+	address := flag.String("address", os.Getenv("TEMPORAL_ADDRESS"), "Temporal server to run against; a dev server is started when empty")
+	namespace := flag.String("namespace", client.DefaultNamespace, "Namespace of the Temporal server given by -address")
+	cliPath := flag.String("temporal-cli", "", "Path to the temporal CLI used for the dev server (downloaded when empty)")
+	outDir := flag.String("out", filepath.Join("tests", "testdata", "histories", Generation), "Directory the histories are written to")
+	only := flag.String("scenario", "", "Capture a single scenario (completed, processing-failed, capture-failed, cancelled, cancelled-during-payment or expedited)")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	var c client.Client
+	if *address == "" {
+		server, err := testsuite.StartDevServer(ctx, testsuite.DevServerOptions{
+			ExistingPath: *cliPath,
+			LogLevel:     "error",
+		})
+		if err != nil {
+			log.Fatalf("Unable to start dev server: %v", err)
+		}
+		defer server.Stop()
+		c = server.Client()
+	} else {
+		var err error
+		c, err = client.Dial(client.Options{HostPort: *address, Namespace: *namespace})
+		if err != nil {
+			log.Fatalf("Unable to create Temporal client: %v", err)
+		}
+	}
+	defer c.Close()
+
+	// Validation approves every order after a short delay, so signals can arrive while it runs
+	validation := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+		json.NewEncoder(w).Encode(models.ValidationResponse{Valid: true, Message: "Order validated successfully"})
+	}))
+	defer validation.Close()
+
+	// Each gateway call takes a while too, so a signal can arrive while the payment runs
+	fake := gateway.NewFakeGateway()
+	fake.Latency = 500 * time.Millisecond
+
+	w := worker.New(c, TaskQueueName, worker.Options{})
+	w.RegisterWorkflow(workflows.OrderWorkflow)
+	w.RegisterWorkflow(workflows.PaymentWorkflow)
+	w.RegisterActivity(&captureActivities{Activities: activities.NewActivities(validation.URL)})
+	w.RegisterActivity(activities.NewPaymentActivities(&captureGateway{PaymentGateway: fake}))
+	if err := w.Start(); err != nil {
+		log.Fatalf("Unable to start worker: %v", err)
+	}
+	defer w.Stop()
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatalf("Unable to create output directory: %v", err)
+	}
+
+	for _, sc := range scenarios {
+		if *only != "" && sc.name != *only {
+			continue
+		}
+		if err := capture(ctx, c, sc, *outDir); err != nil {
+			log.Fatalf("Failed to capture %s: %v", sc.name, err)
+		}
+	}
+}
+
+// capture runs a scenario to completion and writes its history
+func capture(ctx context.Context, c client.Client, sc scenario, outDir string) error {
+	order := sampleOrder(fmt.Sprintf("replay-%s", sc.name))
+	options := client.StartWorkflowOptions{
+		ID:        workflows.OrderWorkflowID(order.ID),
+		TaskQueue: TaskQueueName,
+	}
+
+	log.Printf("Capturing %s as %s", sc.name, options.ID)
+
+	var run client.WorkflowRun
+	var err error
+	if sc.startSignal != "" {
+		run, err = c.SignalWithStartWorkflow(ctx, options.ID, sc.startSignal, sc.startSignal, options, workflows.OrderWorkflow, order)
+	} else {
+		run, err = c.ExecuteWorkflow(ctx, options, workflows.OrderWorkflow, order)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to start workflow: %w", err)
+	}
+
+	if sc.signal != "" {
+		time.Sleep(sc.signalAfter)
+		if err := c.SignalWorkflow(ctx, run.GetID(), run.GetRunID(), sc.signal, sc.signal); err != nil {
+			return fmt.Errorf("unable to signal workflow: %w", err)
+		}
+	}
+
+	// Failed and cancelled orders are valid histories too
+	if err := run.Get(ctx, nil); err != nil {
+		log.Printf("Workflow %s completed with error: %v", run.GetID(), err)
+	}
+
+	if err := writeHistory(ctx, c, run.GetID(), run.GetRunID(), filepath.Join(outDir, sc.name+".json")); err != nil {
+		return err
+	}
+	if sc.child {
+		return writeHistory(ctx, c, workflows.PaymentWorkflowID(order.ID), "", filepath.Join(outDir, sc.name+"-payment.json"))
+	}
+	return nil
+}
+
+// writeHistory exports the full history of a closed workflow run (the latest when runID is empty) in the JSON format of the temporal CLI
+func writeHistory(ctx context.Context, c client.Client, workflowID, runID, path string) error {
+	iter := c.GetWorkflowHistory(ctx, workflowID, runID, false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+
+	history := &historypb.History{}
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("unable to read history of %s: %w", workflowID, err)
+		}
+		history.Events = append(history.Events, event)
+	}
+
+	data, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("unable to encode history of %s: %w", workflowID, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+
+	log.Printf("Wrote %d events to %s", len(history.Events), path)
+	return nil
+}
+
+// sampleOrder builds an order whose items add up to its amount
+func sampleOrder(orderID string) models.Order {
+	return models.Order{
+		ID:     orderID,
+		Amount: models.NewMoneyFromMajor(1000, models.DefaultCurrency),
+		Items: []models.OrderItem{
+			{ProductID: "PROD-001", Name: "Sample Product 1", Quantity: 2, Price: models.NewMoneyFromMajor(300, models.DefaultCurrency)},
+			{ProductID: "PROD-002", Name: "Sample Product 2", Quantity: 1, Price: models.NewMoneyFromMajor(400, models.DefaultCurrency)},
+		},
+		Status: models.OrderStatusPending,
+	}
+}
+
+// captureActivities fails processing of the processing-failed scenario
+type captureActivities struct {
+	*activities.Activities
+}
+
+func (a *captureActivities) ProcessOrder(ctx context.Context, order models.Order) error {
+	if order.ID == "replay-processing-failed" {
+		return temporal.NewNonRetryableApplicationError("inventory unavailable", "OutOfStock", nil)
+	}
+	return a.Activities.ProcessOrder(ctx, order)
+}
+
+// captureGateway declines the capture of the capture-failed scenario
+type captureGateway struct {
+	gateway.PaymentGateway
+}
+
+func (g *captureGateway) Capture(ctx context.Context, req gateway.CaptureRequest) (gateway.Payment, error) {
+	if req.OrderID == "replay-capture-failed" {
+		return gateway.Payment{}, fmt.Errorf("%w: capture rejected", gateway.ErrDeclined)
+	}
+	return g.PaymentGateway.Capture(ctx, req)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/logging"
+
+	"go.temporal.io/sdk/converter"
+)
+
+func main() {
+	// Log through slog as configured by LOG_LEVEL and LOG_FORMAT, JSON at info by default
+	logOptions, err := logging.OptionsFromEnv(logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON})
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	slog.SetDefault(logging.NewSlogLogger(os.Stderr, logOptions))
+
+	// Get listen address from environment or use default
+	listenAddress := os.Getenv("CODEC_SERVER_ADDRESS")
+	if listenAddress == "" {
+		listenAddress = ":8888"
+	}
+
+	// Get the browser origins allowed to call the server, the Temporal UI by default
+	origins := os.Getenv("CODEC_SERVER_ORIGINS")
+	if origins == "" {
+		origins = "http://localhost:8080"
+	}
+
+	// Offloaded payloads are fetched from the same blob store as the worker's
+	blobStore, err := codec.LoadBlobStore()
+	if err != nil {
+		fatal("Failed to create claim-check blob store", err)
+	}
+	newBuilder := func(keyring *codec.Keyring) *codec.DataConverterBuilder {
+		builder := codec.NewDataConverterBuilder().WithKeyring(keyring).WithKeyProvider(codec.NewKeyringKeyProvider(keyring))
+		if blobStore != nil {
+			builder.WithClaimCheck(blobStore, codec.DefaultClaimCheckThreshold)
+		}
+		return builder
+	}
+
+	options := codec.CodecServerOptions{
+		Codecs:         map[string][]converter.PayloadCodec{},
+		AuthToken:      os.Getenv("CODEC_SERVER_AUTH_TOKEN"),
+		AllowedOrigins: strings.Split(origins, ","),
+	}
+
+	// Requests must carry the token unless authentication is turned off explicitly
+	if options.AuthToken == "" {
+		if os.Getenv("CODEC_SERVER_AUTH_DISABLED") != "true" {
+			fatal("No auth token configured", errors.New("set CODEC_SERVER_AUTH_TOKEN, or CODEC_SERVER_AUTH_DISABLED=true for local development"))
+		}
+		options.DisableAuth = true
+	}
+
+	// Namespaces with their own keys have a <namespace>.json keyring file in CODEC_SERVER_KEYRING_DIR
+	if dir := os.Getenv("CODEC_SERVER_KEYRING_DIR"); dir != "" {
+		keyrings, err := codec.LoadKeyringDir(dir)
+		if err != nil {
+			fatal("Failed to load namespace keyrings", err)
+		}
+		for namespace, keyring := range keyrings {
+			codecs, err := newBuilder(keyring).Codecs()
+			if err != nil {
+				fatal("Failed to create codecs of namespace "+namespace, err)
+			}
+			options.Codecs[namespace] = codecs
+		}
+	}
+
+	// Every other namespace uses the encryption setup of the worker and starter
+	encryption, err := codec.LoadEncryptionConfig()
+	switch {
+	case err == nil:
+		if encryption.GeneratedKey != nil {
+			slog.Warn("Dev mode generated a key no worker shares, payloads cannot be decoded")
+		}
+		options.DefaultCodecs, err = encryption.Builder().Codecs()
+		if err != nil {
+			fatal("Failed to create codecs", err)
+		}
+	case !errors.Is(err, codec.ErrNoEncryptionKey):
+		fatal("Failed to load encryption config", err)
+	case len(options.Codecs) == 0:
+		fatal("No encryption keys configured", errors.New("set ENCRYPTION_KEY to the key of the worker, or CODEC_SERVER_KEYRING_DIR"))
+	}
+
+	namespaces := make([]string, 0, len(options.Codecs))
+	for namespace := range options.Codecs {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	authentication := "disabled"
+	if options.AuthToken != "" {
+		authentication = "bearer token"
+	}
+	slog.Info("Starting codec server",
+		"listen", listenAddress,
+		"allowed_origins", origins,
+		"namespace_keyrings", namespaces,
+		"authentication", authentication,
+	)
+	switch {
+	case len(options.DefaultCodecs) == 0:
+		slog.Info("No default keyring, other namespaces are rejected")
+	case encryption.Keyring != nil:
+		activeKeyID, _ := encryption.Keyring.ActiveKey()
+		slog.Info("Default keyring", "master_key", activeKeyID, "keys", encryption.Keyring.IDs())
+	}
+	if kmsEndpoint := os.Getenv("ENCRYPTION_KMS_ENDPOINT"); kmsEndpoint != "" {
+		slog.Info("Data keys unwrapped by KMS", "kms_endpoint", kmsEndpoint)
+	}
+
+	server, err := codec.NewCodecServer(options)
+	if err != nil {
+		fatal("Failed to create codec server", err)
+	}
+	if err := http.ListenAndServe(listenAddress, server); err != nil {
+		fatal("Codec server failed", err)
+	}
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,218 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrBlobNotFound is returned when a blob store has no blob under a key
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores the payloads offloaded by the claim-check codec
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// FileBlobStore stores blobs as files in a directory
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore creates a blob store in dir, creating the directory if needed
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FileBlobStore{dir: dir}, nil
+}
+
+// Put writes a blob; the file appears under its final name only once fully written
+func (s *FileBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get reads a blob
+func (s *FileBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// path maps a key to a file in the store directory
+func (s *FileBlobStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// ObjectClient is the part of an S3-compatible object storage client the blob store needs.
+// An adapter over the AWS SDK satisfies it as well as HTTPObjectClient.
+type ObjectClient interface {
+	PutObject(ctx context.Context, bucket, key string, data []byte) error
+	// GetObject returns ErrBlobNotFound when the object does not exist
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+}
+
+// S3BlobStore stores blobs as objects in an S3-compatible bucket
+type S3BlobStore struct {
+	client ObjectClient
+	bucket string
+	prefix string
+}
+
+// NewS3BlobStore creates a blob store that keeps blobs under prefix in bucket
+func NewS3BlobStore(client ObjectClient, bucket, prefix string) *S3BlobStore {
+	return &S3BlobStore{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+// Put uploads a blob
+func (s *S3BlobStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := s.client.PutObject(ctx, s.bucket, s.prefix+key, data); err != nil {
+		return fmt.Errorf("failed to upload blob %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get downloads a blob
+func (s *S3BlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.GetObject(ctx, s.bucket, s.prefix+key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// HTTPObjectClient talks to an S3-compatible endpoint with unsigned path-style requests,
+// as accepted by local stand-ins such as S3Mock
+type HTTPObjectClient struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewHTTPObjectClient creates an object client for the endpoint
+func NewHTTPObjectClient(endpoint string) *HTTPObjectClient {
+	return &HTTPObjectClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// PutObject uploads an object
+func (c *HTTPObjectClient) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.objectURL(bucket, key), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// GetObject downloads an object
+func (c *HTTPObjectClient) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s/%s", ErrBlobNotFound, bucket, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
+
+// objectURL is the path-style URL of an object
+func (c *HTTPObjectClient) objectURL(bucket, key string) string {
+	return c.endpoint + "/" + url.PathEscape(bucket) + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+// LoadBlobStore creates the blob store configured by environment variables, or returns nil
+// when claim-checking is disabled:
+//   - CLAIM_CHECK_S3_ENDPOINT and CLAIM_CHECK_S3_BUCKET: an S3-compatible bucket
+//   - CLAIM_CHECK_DIR: a local directory, shared by the worker, starter and codec server
+func LoadBlobStore() (BlobStore, error) {
+	if endpoint := os.Getenv("CLAIM_CHECK_S3_ENDPOINT"); endpoint != "" {
+		bucket := os.Getenv("CLAIM_CHECK_S3_BUCKET")
+		if bucket == "" {
+			return nil, fmt.Errorf("CLAIM_CHECK_S3_BUCKET must be set with CLAIM_CHECK_S3_ENDPOINT")
+		}
+		return NewS3BlobStore(NewHTTPObjectClient(endpoint), bucket, "payloads/"), nil
+	}
+	if dir := os.Getenv("CLAIM_CHECK_DIR"); dir != "" {
+		return NewFileBlobStore(dir)
+	}
+	return nil, nil
+}
@@ -0,0 +1,101 @@
+package codec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MetadataEncodingClaimCheck is the encoding type for payloads offloaded to a blob store;
+	// the payload data is the blob key
+	MetadataEncodingClaimCheck = "binary/claim-check"
+
+	// DefaultClaimCheckThreshold is the serialized payload size from which payloads are offloaded,
+	// well below the 2 MB Temporal payload limit
+	DefaultClaimCheckThreshold = 256 * 1024
+)
+
+// ClaimCheckCodec implements converter.PayloadCodec for offloading large payloads to a blob
+// store and keeping only a reference in the workflow history
+type ClaimCheckCodec struct {
+	store     BlobStore
+	threshold int
+}
+
+// NewClaimCheckCodec creates a claim-check codec that offloads payloads of at least threshold bytes
+func NewClaimCheckCodec(store BlobStore, threshold int) *ClaimCheckCodec {
+	return &ClaimCheckCodec{
+		store:     store,
+		threshold: threshold,
+	}
+}
+
+// Encode stores the payloads that reach the threshold and replaces them with their key.
+// Keys are the SHA-256 of the stored bytes, so encoding the same payload again stores nothing new.
+func (c *ClaimCheckCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+
+	for i, payload := range payloads {
+		result[i] = payload
+		if proto.Size(payload) < c.threshold {
+			continue
+		}
+
+		data, err := marshalPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		key := hex.EncodeToString(sum[:])
+		if err := c.store.Put(context.Background(), key, data); err != nil {
+			return nil, fmt.Errorf("failed to offload payload: %w", err)
+		}
+
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				"encoding": []byte(MetadataEncodingClaimCheck),
+			},
+			Data: []byte(key),
+		}
+	}
+
+	return result, nil
+}
+
+// Decode fetches offloaded payloads from the blob store
+func (c *ClaimCheckCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+
+	for i, payload := range payloads {
+		// Skip if not offloaded
+		if payload.Metadata == nil || string(payload.Metadata["encoding"]) != MetadataEncodingClaimCheck {
+			result[i] = payload
+			continue
+		}
+
+		key := string(payload.Data)
+		data, err := c.store.Get(context.Background(), key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch offloaded payload: %w", err)
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != key {
+			return nil, fmt.Errorf("offloaded payload %s does not match its key", key)
+		}
+
+		original, err := unmarshalPayload(data)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = original
+	}
+
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package codec
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// NamespaceHeader is the header the Temporal UI and CLI use to name the namespace of the payloads
+	NamespaceHeader = "X-Namespace"
+)
+
+// CodecServerOptions configures a CodecServer
+type CodecServerOptions struct {
+	// Codecs are the codec chains of namespaces that have their own keys, in the order of
+	// DataConverterBuilder.Codecs
+	Codecs map[string][]converter.PayloadCodec
+	// DefaultCodecs serve every other namespace; they are rejected when it is empty
+	DefaultCodecs []converter.PayloadCodec
+	// AuthToken is the bearer token requests must carry
+	AuthToken string
+	// DisableAuth serves requests without a token when AuthToken is empty, for local development
+	DisableAuth bool
+	// AllowedOrigins are the browser origins, such as the Temporal UI, allowed to call the
+	// server. "*" allows every origin, but browsers then send no credentials.
+	AllowedOrigins []string
+}
+
+// CodecServer serves payload codecs over the /encode and /decode endpoints of the
+// Temporal remote codec protocol
+type CodecServer struct {
+	handlers       map[string]http.Handler
+	defaultHandler http.Handler
+	authToken      string
+	allowedOrigins map[string]bool
+}
+
+// NewCodecServer creates a codec server. It fails without an AuthToken unless DisableAuth is set.
+func NewCodecServer(opts CodecServerOptions) (*CodecServer, error) {
+	if opts.AuthToken == "" && !opts.DisableAuth {
+		return nil, fmt.Errorf("codec server needs an auth token unless authentication is disabled")
+	}
+
+	s := &CodecServer{
+		handlers:       make(map[string]http.Handler, len(opts.Codecs)),
+		authToken:      opts.AuthToken,
+		allowedOrigins: make(map[string]bool, len(opts.AllowedOrigins)),
+	}
+	for namespace, codecs := range opts.Codecs {
+		s.handlers[namespace] = converter.NewPayloadCodecHTTPHandler(codecs...)
+	}
+	if len(opts.DefaultCodecs) > 0 {
+		s.defaultHandler = converter.NewPayloadCodecHTTPHandler(opts.DefaultCodecs...)
+	}
+	for _, origin := range opts.AllowedOrigins {
+		s.allowedOrigins[origin] = true
+	}
+	return s, nil
+}
+
+// ServeHTTP handles CORS and authentication, then encodes or decodes with the codec of the request's namespace
+func (s *CodecServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		switch {
+		case s.allowedOrigins[origin]:
+			// Only origins allowed by name may send credentials
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Add("Vary", "Origin")
+		case s.allowedOrigins["*"]:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+NamespaceHeader)
+		}
+	}
+
+	// Preflight requests carry no credentials
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	namespace := r.Header.Get(NamespaceHeader)
+	handler, ok := s.handlers[namespace]
+	if !ok {
+		handler = s.defaultHandler
+	}
+	if handler == nil {
+		http.Error(w, "no codec for namespace "+namespace, http.StatusForbidden)
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// authorized checks the bearer token of a request
+func (s *CodecServer) authorized(r *http.Request) bool {
+	if s.authToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1
+}
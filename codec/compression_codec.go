@@ -0,0 +1,117 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	commonpb "go.temporal.io/api/common/v1"
+)
+
+const (
+	// MetadataEncodingGzip is the encoding type for compressed payloads
+	MetadataEncodingGzip = "binary/gzip"
+
+	// DefaultCompressionThreshold is the serialized payload size from which payloads are compressed
+	DefaultCompressionThreshold = 1024
+
+	// DefaultMaxDecompressedSize is the largest payload Decode decompresses, so a small
+	// compressed payload cannot expand without limit
+	DefaultMaxDecompressedSize = 16 << 20
+)
+
+// CompressionCodec implements converter.PayloadCodec for compressing large workflow data with gzip
+type CompressionCodec struct {
+	threshold         int
+	maxDecompressSize int
+}
+
+// NewCompressionCodec creates a compression codec that compresses payloads of at least threshold
+// bytes and decompresses payloads of up to DefaultMaxDecompressedSize bytes
+func NewCompressionCodec(threshold int) *CompressionCodec {
+	return NewCompressionCodecWithLimit(threshold, DefaultMaxDecompressedSize)
+}
+
+// NewCompressionCodecWithLimit creates a compression codec that compresses payloads of at least
+// threshold bytes and fails to decode payloads that decompress to more than maxDecompressedSize bytes
+func NewCompressionCodecWithLimit(threshold, maxDecompressedSize int) *CompressionCodec {
+	return &CompressionCodec{
+		threshold:         threshold,
+		maxDecompressSize: maxDecompressedSize,
+	}
+}
+
+// Encode compresses the payloads that reach the threshold and get smaller when compressed
+func (c *CompressionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+
+	for i, payload := range payloads {
+		result[i] = payload
+
+		data, err := marshalPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < c.threshold {
+			continue
+		}
+
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress payload: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress payload: %w", err)
+		}
+
+		// Keep payloads that do not compress, such as already encrypted ones, as they are
+		if compressed.Len() >= len(data) {
+			continue
+		}
+
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				"encoding": []byte(MetadataEncodingGzip),
+			},
+			Data: compressed.Bytes(),
+		}
+	}
+
+	return result, nil
+}
+
+// Decode decompresses the provided payloads, failing for payloads larger than the limit
+func (c *CompressionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+
+	for i, payload := range payloads {
+		// Skip if not compressed
+		if payload.Metadata == nil || string(payload.Metadata["encoding"]) != MetadataEncodingGzip {
+			result[i] = payload
+			continue
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(payload.Data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+		// Read one byte past the limit to tell a payload of exactly the limit from a larger one
+		data, err := io.ReadAll(io.LimitReader(zr, int64(c.maxDecompressSize)+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+		if len(data) > c.maxDecompressSize {
+			return nil, fmt.Errorf("failed to decompress payload: larger than %d bytes", c.maxDecompressSize)
+		}
+
+		original, err := unmarshalPayload(data)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = original
+	}
+
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package codec
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// EncryptionConfig is the payload encryption setup shared by the worker, starter and codec server
+type EncryptionConfig struct {
+	// Keyring holds the local keys. It is the master keyring of the envelope encryption without
+	// a KMS, and decrypts payloads encrypted with it directly before envelope encryption.
+	Keyring *Keyring
+	// KeyProvider issues the data keys new payloads are encrypted with
+	KeyProvider KeyProvider
+	// BlobStore receives large payloads; claim-checking is disabled when nil
+	BlobStore BlobStore
+	// GeneratedKey is the throwaway key created in dev mode when no key is configured
+	GeneratedKey []byte
+	// FieldLevel encrypts only PII fields instead of whole payloads
+	FieldLevel bool
+	// MaxDecompressedSize is the largest size a compressed payload may decompress to
+	MaxDecompressedSize int
+}
+
+// LoadEncryptionConfig loads the encryption setup from environment variables:
+//   - ENCRYPTION_KMS_ENDPOINT: a KMS issuing data keys; the local keyring is then optional
+//   - ENCRYPTION_KEYRING_FILE or ENCRYPTION_KEY: the local keyring, see LoadKeyring
+//   - ENCRYPTION_DEV_MODE: when true, a random key is generated if none is configured
+//   - ENCRYPTION_MODE: "payload" (default) encrypts whole payloads, "fields" only PII fields
+//   - CLAIM_CHECK_*: the claim-check blob store, see LoadBlobStore
+//   - COMPRESSION_MAX_DECOMPRESSED_BYTES: the largest size a compressed payload may decompress to
+//
+// Without keys, loading fails with ErrNoEncryptionKey: a worker and a starter that each
+// generate a key cannot read each other's payloads.
+func LoadEncryptionConfig() (*EncryptionConfig, error) {
+	config := &EncryptionConfig{MaxDecompressedSize: DefaultMaxDecompressedSize}
+
+	switch mode := os.Getenv("ENCRYPTION_MODE"); mode {
+	case "", "payload":
+	case "fields":
+		config.FieldLevel = true
+	default:
+		return nil, fmt.Errorf("invalid ENCRYPTION_MODE %q: use payload or fields", mode)
+	}
+
+	if value := os.Getenv("COMPRESSION_MAX_DECOMPRESSED_BYTES"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid COMPRESSION_MAX_DECOMPRESSED_BYTES %q: use a positive number of bytes", value)
+		}
+		config.MaxDecompressedSize = size
+	}
+
+	keyring, err := LoadKeyring()
+	switch {
+	case err == nil:
+		config.Keyring = keyring
+	case !errors.Is(err, ErrNoEncryptionKey):
+		return nil, err
+	}
+
+	if endpoint := os.Getenv("ENCRYPTION_KMS_ENDPOINT"); endpoint != "" {
+		config.KeyProvider = NewHTTPKeyProvider(endpoint)
+	} else {
+		if config.Keyring == nil {
+			devMode, _ := strconv.ParseBool(os.Getenv("ENCRYPTION_DEV_MODE"))
+			if !devMode {
+				return nil, fmt.Errorf("%w: set ENCRYPTION_KEY, ENCRYPTION_KEYRING_FILE or ENCRYPTION_KMS_ENDPOINT, or ENCRYPTION_DEV_MODE=true to generate a throwaway key", ErrNoEncryptionKey)
+			}
+
+			// Generate a random 32-byte key for AES-256
+			config.GeneratedKey = make([]byte, 32)
+			if _, err := rand.Read(config.GeneratedKey); err != nil {
+				return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+			}
+			config.Keyring, err = NewKeyring(DefaultKeyID, map[string][]byte{DefaultKeyID: config.GeneratedKey})
+			if err != nil {
+				return nil, err
+			}
+		}
+		config.KeyProvider = NewKeyringKeyProvider(config.Keyring)
+	}
+
+	config.BlobStore, err = LoadBlobStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create claim-check blob store: %w", err)
+	}
+
+	return config, nil
+}
+
+// Builder returns a data converter builder for the configuration
+func (c *EncryptionConfig) Builder() *DataConverterBuilder {
+	builder := NewDataConverterBuilder().WithKeyProvider(c.KeyProvider)
+	if c.MaxDecompressedSize > 0 {
+		builder.WithMaxDecompressedSize(c.MaxDecompressedSize)
+	}
+	if c.Keyring != nil {
+		builder.WithKeyring(c.Keyring)
+	}
+	if c.FieldLevel {
+		builder.WithFieldEncryption()
+	}
+	if c.BlobStore != nil {
+		builder.WithClaimCheck(c.BlobStore, DefaultClaimCheckThreshold)
+	}
+	return builder
+}
@@ -0,0 +1,192 @@
+package codec
+
+import (
+	"fmt"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DataConverterBuilder assembles the codec chain of the data converter. Payloads are
+// compressed before they are encrypted, since ciphertext does not compress, and only
+// encrypted bytes are offloaded to the claim-check blob store.
+type DataConverterBuilder struct {
+	key                  []byte
+	keyring              *Keyring
+	keyProvider          KeyProvider
+	fieldEncryption      bool
+	compress             bool
+	compressionThreshold int
+	maxDecompressedSize  int
+	blobStore            BlobStore
+	claimCheckThreshold  int
+}
+
+// NewDataConverterBuilder creates a builder that compresses payloads from DefaultCompressionThreshold
+// bytes and decompresses payloads of up to DefaultMaxDecompressedSize bytes
+func NewDataConverterBuilder() *DataConverterBuilder {
+	return &DataConverterBuilder{
+		compress:             true,
+		compressionThreshold: DefaultCompressionThreshold,
+		maxDecompressedSize:  DefaultMaxDecompressedSize,
+	}
+}
+
+// WithKey encrypts with a single 32-byte key
+func (b *DataConverterBuilder) WithKey(key []byte) *DataConverterBuilder {
+	b.key = key
+	return b
+}
+
+// WithKeyring encrypts with the active key of the keyring
+func (b *DataConverterBuilder) WithKeyring(keyring *Keyring) *DataConverterBuilder {
+	b.keyring = keyring
+	return b
+}
+
+// WithKeyProvider encrypts with envelope encryption using data keys from the provider. A key
+// or keyring set as well only decrypts payloads encrypted with it directly.
+func (b *DataConverterBuilder) WithKeyProvider(provider KeyProvider) *DataConverterBuilder {
+	b.keyProvider = provider
+	return b
+}
+
+// WithFieldEncryption encrypts only the fields tagged `pii:"true"` instead of whole payloads,
+// see FieldEncryptionConverter. Payloads encrypted whole are still decrypted.
+func (b *DataConverterBuilder) WithFieldEncryption() *DataConverterBuilder {
+	b.fieldEncryption = true
+	return b
+}
+
+// WithCompressionThreshold compresses payloads of at least threshold bytes
+func (b *DataConverterBuilder) WithCompressionThreshold(threshold int) *DataConverterBuilder {
+	b.compress = true
+	b.compressionThreshold = threshold
+	return b
+}
+
+// WithMaxDecompressedSize fails to decode compressed payloads larger than size bytes once decompressed
+func (b *DataConverterBuilder) WithMaxDecompressedSize(size int) *DataConverterBuilder {
+	b.maxDecompressedSize = size
+	return b
+}
+
+// WithoutCompression only encrypts payloads
+func (b *DataConverterBuilder) WithoutCompression() *DataConverterBuilder {
+	b.compress = false
+	return b
+}
+
+// WithClaimCheck offloads encrypted payloads of at least threshold bytes to the blob store
+func (b *DataConverterBuilder) WithClaimCheck(store BlobStore, threshold int) *DataConverterBuilder {
+	b.blobStore = store
+	b.claimCheckThreshold = threshold
+	return b
+}
+
+// Codecs returns the codec chain in the order converter.NewCodecDataConverter and
+// converter.NewPayloadCodecHTTPHandler expect: encoding runs from the last codec to the
+// first, decoding from the first to the last
+func (b *DataConverterBuilder) Codecs() ([]converter.PayloadCodec, error) {
+	keyring, err := b.resolveKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	var codecs []converter.PayloadCodec
+	if b.blobStore != nil {
+		codecs = append(codecs, NewClaimCheckCodec(b.blobStore, b.claimCheckThreshold))
+	}
+	var encryption []converter.PayloadCodec
+	switch {
+	case b.keyProvider != nil && keyring != nil:
+		encryption = append(encryption, NewEnvelopeCodec(b.keyProvider), decodeOnlyCodec{NewKeyringEncryptionCodec(keyring)})
+	case b.keyProvider != nil:
+		encryption = append(encryption, NewEnvelopeCodec(b.keyProvider))
+	default:
+		encryption = append(encryption, NewKeyringEncryptionCodec(keyring))
+	}
+	for _, c := range encryption {
+		// Field-level encryption happens in the payload converter; whole payloads are only decrypted
+		if b.fieldEncryption {
+			c = decodeOnlyCodec{c}
+		}
+		codecs = append(codecs, c)
+	}
+	if b.compress {
+		codecs = append(codecs, NewCompressionCodecWithLimit(b.compressionThreshold, b.maxDecompressedSize))
+	}
+	return codecs, nil
+}
+
+// Build creates the data converter
+func (b *DataConverterBuilder) Build() (converter.DataConverter, error) {
+	codecs, err := b.Codecs()
+	if err != nil {
+		return nil, err
+	}
+
+	parent := converter.GetDefaultDataConverter()
+	if b.fieldEncryption {
+		keyring, err := b.resolveKeyring()
+		if err != nil {
+			return nil, err
+		}
+		provider := b.keyProvider
+		if provider == nil {
+			provider = NewKeyringKeyProvider(keyring)
+		}
+
+		// The default converters, with the JSON converter encrypting PII fields
+		parent = converter.NewCompositeDataConverter(
+			converter.NewNilPayloadConverter(),
+			converter.NewByteSlicePayloadConverter(),
+			converter.NewProtoJSONPayloadConverter(),
+			converter.NewProtoPayloadConverter(),
+			NewFieldEncryptionConverter(provider),
+		)
+	}
+
+	dataConverter := converter.NewCodecDataConverter(
+		parent,
+		codecs...,
+	)
+	if b.keyProvider != nil || b.blobStore != nil {
+		// A conversion may wait on the key provider or the blob store for up to their
+		// timeouts, longer than the deadlock detector lets a workflow task block
+		dataConverter = workflow.DataConverterWithoutDeadlockDetection(dataConverter)
+	}
+	return dataConverter, nil
+}
+
+// resolveKeyring returns the keyring, built from the single key when only that is set
+func (b *DataConverterBuilder) resolveKeyring() (*Keyring, error) {
+	keyring := b.keyring
+	if keyring == nil && b.key != nil {
+		var err error
+		keyring, err = NewKeyring(DefaultKeyID, map[string][]byte{DefaultKeyID: b.key})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if keyring == nil && b.keyProvider == nil {
+		return nil, fmt.Errorf("data converter needs an encryption key, keyring or key provider")
+	}
+	return keyring, nil
+}
+
+// NewEncryptionDataConverter creates a data converter that compresses and encrypts with the key
+func NewEncryptionDataConverter(key []byte) (converter.DataConverter, error) {
+	return NewDataConverterBuilder().WithKey(key).Build()
+}
+
+// decodeOnlyCodec decodes with the wrapped codec but leaves payloads to encode untouched
+type decodeOnlyCodec struct {
+	converter.PayloadCodec
+}
+
+// Encode returns the payloads unchanged
+func (c decodeOnlyCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	return payloads, nil
+}
@@ -0,0 +1,131 @@
+package codec
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDataKeyRotation is how long a data key encrypts fields before it is replaced
+	DefaultDataKeyRotation = 10 * time.Minute
+	// DefaultKeyProviderTimeout bounds every call to the KeyProvider of a dataKeyCache
+	DefaultKeyProviderTimeout = 10 * time.Second
+
+	// maxUnwrappedDataKeys bounds the unwrapped data keys kept for decryption
+	maxUnwrappedDataKeys = 1024
+)
+
+// dataKeyCache reuses a data key until it is older than rotation and keeps unwrapped data
+// keys, so converting a payload does not call the KeyProvider. A key due for rotation is
+// replaced in the background while it is still used; only the first key, or a key left
+// unreplaced for a second rotation period, is generated during a conversion.
+type dataKeyCache struct {
+	provider KeyProvider
+	rotation time.Duration
+	timeout  time.Duration
+
+	mu         sync.Mutex
+	current    *DataKey
+	created    time.Time
+	rotating   bool
+	unwrapped  map[string][]byte
+	unwrapKeys []string
+}
+
+// newDataKeyCache creates a cache of data keys from the provider
+func newDataKeyCache(provider KeyProvider, rotation time.Duration) *dataKeyCache {
+	return &dataKeyCache{
+		provider:  provider,
+		rotation:  rotation,
+		timeout:   DefaultKeyProviderTimeout,
+		unwrapped: map[string][]byte{},
+	}
+}
+
+// dataKey returns the data key to encrypt with
+func (c *dataKeyCache) dataKey() (DataKey, error) {
+	c.mu.Lock()
+	current, age := c.current, time.Since(c.created)
+	if current != nil && age >= c.rotation && !c.rotating {
+		c.rotating = true
+		go c.rotate()
+	}
+	c.mu.Unlock()
+
+	if current != nil && age < 2*c.rotation {
+		return *current, nil
+	}
+	return c.generate()
+}
+
+// rotate replaces the current data key; on failure the current key stays in use and the
+// next conversion tries again
+func (c *dataKeyCache) rotate() {
+	_, _ = c.generate()
+
+	c.mu.Lock()
+	c.rotating = false
+	c.mu.Unlock()
+}
+
+// generate asks the provider for a new data key and makes it the current one
+func (c *dataKeyCache) generate() (DataKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	key, err := c.provider.GenerateDataKey(ctx)
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = &key
+	c.created = time.Now()
+	c.remember(key.MasterKeyID, key.Wrapped, key.Plaintext)
+	return key, nil
+}
+
+// decrypt returns the unwrapped data key, asking the provider only for keys it has not seen
+func (c *dataKeyCache) decrypt(masterKeyID string, wrapped []byte) ([]byte, error) {
+	c.mu.Lock()
+	plaintext, ok := c.unwrapped[unwrapKey(masterKeyID, wrapped)]
+	c.mu.Unlock()
+	if ok {
+		return plaintext, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	plaintext, err := c.provider.DecryptDataKey(ctx, masterKeyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remember(masterKeyID, wrapped, plaintext)
+	return plaintext, nil
+}
+
+// remember keeps an unwrapped data key, dropping the oldest beyond maxUnwrappedDataKeys.
+// The caller holds mu.
+func (c *dataKeyCache) remember(masterKeyID string, wrapped, plaintext []byte) {
+	key := unwrapKey(masterKeyID, wrapped)
+	if _, ok := c.unwrapped[key]; ok {
+		return
+	}
+	if len(c.unwrapKeys) >= maxUnwrappedDataKeys {
+		delete(c.unwrapped, c.unwrapKeys[0])
+		c.unwrapKeys = c.unwrapKeys[1:]
+	}
+	c.unwrapped[key] = plaintext
+	c.unwrapKeys = append(c.unwrapKeys, key)
+}
+
+// unwrapKey identifies a wrapped data key
+func unwrapKey(masterKeyID string, wrapped []byte) string {
+	return masterKeyID + "/" + string(wrapped)
+}
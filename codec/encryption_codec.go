@@ -8,34 +8,48 @@ import (
 	"io"
 
 	commonpb "go.temporal.io/api/common/v1"
-	"go.temporal.io/sdk/converter"
 )
 
 const (
 	// MetadataEncodingEncrypted is the encoding type for encrypted payloads
 	MetadataEncodingEncrypted = "binary/encrypted"
+	// MetadataEncryptionKeyID is the metadata field naming the key a payload was encrypted with
+	MetadataEncryptionKeyID = "encryption-key-id"
+	// MetadataEncryptionFormat is the metadata field describing what was encrypted
+	MetadataEncryptionFormat = "encryption-format"
+	// EncryptionFormatPayload marks ciphertext holding the whole serialized original payload,
+	// metadata included. Payloads without a format hold only the original data.
+	EncryptionFormatPayload = "payload"
 )
 
 // EncryptionCodec implements converter.PayloadCodec for encrypting/decrypting workflow data
 type EncryptionCodec struct {
-	key []byte
+	keyring *Keyring
 }
 
 // NewEncryptionCodec creates a new encryption codec with the provided key
 // The key should be 32 bytes for AES-256
 func NewEncryptionCodec(key []byte) (*EncryptionCodec, error) {
-	if len(key) != 32 {
-		return nil, fmt.Errorf("key must be 32 bytes for AES-256, got %d bytes", len(key))
+	keyring, err := NewKeyring(DefaultKeyID, map[string][]byte{DefaultKeyID: key})
+	if err != nil {
+		return nil, err
 	}
 
+	return NewKeyringEncryptionCodec(keyring), nil
+}
+
+// NewKeyringEncryptionCodec creates an encryption codec that encrypts with the active key
+// of the keyring and decrypts with whichever key a payload names
+func NewKeyringEncryptionCodec(keyring *Keyring) *EncryptionCodec {
 	return &EncryptionCodec{
-		key: key,
-	}, nil
+		keyring: keyring,
+	}
 }
 
 // Encode encrypts the provided payloads
 func (e *EncryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
 	result := make([]*commonpb.Payload, len(payloads))
+	keyID, key := e.keyring.ActiveKey()
 
 	for i, payload := range payloads {
 		// Skip if already encrypted
@@ -44,11 +58,13 @@ func (e *EncryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payl
 			continue
 		}
 
-		// Serialize the payload
-		data := payload.Data
+		data, err := marshalPayload(payload)
+		if err != nil {
+			return nil, err
+		}
 
 		// Encrypt the data
-		encrypted, err := e.encrypt(data)
+		encrypted, err := sealGCM(key, data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to encrypt payload: %w", err)
 		}
@@ -56,7 +72,9 @@ func (e *EncryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payl
 		// Create new payload with encrypted data
 		result[i] = &commonpb.Payload{
 			Metadata: map[string][]byte{
-				"encoding": []byte(MetadataEncodingEncrypted),
+				"encoding":               []byte(MetadataEncodingEncrypted),
+				MetadataEncryptionKeyID:  []byte(keyID),
+				MetadataEncryptionFormat: []byte(EncryptionFormatPayload),
 			},
 			Data: encrypted,
 		}
@@ -76,13 +94,29 @@ func (e *EncryptionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payl
 			continue
 		}
 
+		// Pick the key the payload was encrypted with
+		key, err := e.keyFor(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+		}
+
 		// Decrypt the data
-		decrypted, err := e.decrypt(payload.Data)
+		decrypted, err := openGCM(key, payload.Data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decrypt payload: %w", err)
 		}
 
-		// Create new payload with decrypted data
+		// Restore the original payload
+		if string(payload.Metadata[MetadataEncryptionFormat]) == EncryptionFormatPayload {
+			original, err := unmarshalPayload(decrypted)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = original
+			continue
+		}
+
+		// Older payloads only encrypted the data of JSON payloads
 		result[i] = &commonpb.Payload{
 			Metadata: map[string][]byte{
 				"encoding": []byte("json/plain"),
@@ -94,9 +128,22 @@ func (e *EncryptionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payl
 	return result, nil
 }
 
-// encrypt encrypts data using AES-GCM
-func (e *EncryptionCodec) encrypt(plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(e.key)
+// keyFor returns the key named by the payload metadata. Payloads written before key IDs
+// were recorded are decrypted with the DefaultKeyID key, or the active key without one.
+func (e *EncryptionCodec) keyFor(payload *commonpb.Payload) ([]byte, error) {
+	if keyID, ok := payload.Metadata[MetadataEncryptionKeyID]; ok {
+		return e.keyring.Key(string(keyID))
+	}
+	if key, err := e.keyring.Key(DefaultKeyID); err == nil {
+		return key, nil
+	}
+	_, key := e.keyring.ActiveKey()
+	return key, nil
+}
+
+// sealGCM encrypts data using AES-GCM
+func sealGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
@@ -115,9 +162,9 @@ func (e *EncryptionCodec) encrypt(plaintext []byte) ([]byte, error) {
 	return ciphertext, nil
 }
 
-// decrypt decrypts data using AES-GCM
-func (e *EncryptionCodec) decrypt(ciphertext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(e.key)
+// openGCM decrypts data using AES-GCM
+func openGCM(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
@@ -140,16 +187,3 @@ func (e *EncryptionCodec) decrypt(ciphertext []byte) ([]byte, error) {
 
 	return plaintext, nil
 }
-
-// NewEncryptionDataConverter creates a data converter with encryption codec
-func NewEncryptionDataConverter(key []byte) (converter.DataConverter, error) {
-	codec, err := NewEncryptionCodec(key)
-	if err != nil {
-		return nil, err
-	}
-
-	return converter.NewCodecDataConverter(
-		converter.GetDefaultDataConverter(),
-		codec,
-	), nil
-}
@@ -0,0 +1,108 @@
+package codec
+
+import (
+	"fmt"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+)
+
+const (
+	// MetadataEncodingEnvelope is the encoding type for payloads encrypted with a wrapped data key
+	MetadataEncodingEnvelope = "binary/encrypted-envelope"
+	// MetadataEncryptionDataKey is the metadata field holding the wrapped data key of a payload
+	MetadataEncryptionDataKey = "encryption-data-key"
+)
+
+// EnvelopeCodec implements converter.PayloadCodec with envelope encryption: payloads are
+// encrypted with a data key that is stored with them, wrapped by a master key of the
+// KeyProvider. Data keys are reused across payloads until they are rotated and unwrapped
+// data keys are cached, so encoding and decoding during a workflow task do not wait on the
+// KeyProvider.
+type EnvelopeCodec struct {
+	keys *dataKeyCache
+}
+
+// NewEnvelopeCodec creates an envelope encryption codec with data keys from the provider,
+// rotated every DefaultDataKeyRotation
+func NewEnvelopeCodec(provider KeyProvider) *EnvelopeCodec {
+	return NewEnvelopeCodecWithRotation(provider, DefaultDataKeyRotation)
+}
+
+// NewEnvelopeCodecWithRotation creates an envelope encryption codec that replaces its data
+// key once it is older than rotation
+func NewEnvelopeCodecWithRotation(provider KeyProvider, rotation time.Duration) *EnvelopeCodec {
+	return &EnvelopeCodec{
+		keys: newDataKeyCache(provider, rotation),
+	}
+}
+
+// Encode encrypts the provided payloads
+func (e *EnvelopeCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+
+	for i, payload := range payloads {
+		// Skip if already encrypted
+		if encoding := string(payload.Metadata["encoding"]); encoding == MetadataEncodingEnvelope || encoding == MetadataEncodingEncrypted {
+			result[i] = payload
+			continue
+		}
+
+		data, err := marshalPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		dataKey, err := e.keys.dataKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt payload: %w", err)
+		}
+
+		encrypted, err := sealGCM(dataKey.Plaintext, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt payload: %w", err)
+		}
+
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				"encoding":                []byte(MetadataEncodingEnvelope),
+				MetadataEncryptionKeyID:   []byte(dataKey.MasterKeyID),
+				MetadataEncryptionDataKey: dataKey.Wrapped,
+			},
+			Data: encrypted,
+		}
+	}
+
+	return result, nil
+}
+
+// Decode decrypts the provided payloads
+func (e *EnvelopeCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+
+	for i, payload := range payloads {
+		// Skip if not envelope encrypted
+		if payload.Metadata == nil || string(payload.Metadata["encoding"]) != MetadataEncodingEnvelope {
+			result[i] = payload
+			continue
+		}
+
+		key, err := e.keys.decrypt(string(payload.Metadata[MetadataEncryptionKeyID]), payload.Metadata[MetadataEncryptionDataKey])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+		}
+
+		decrypted, err := openGCM(key, payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+		}
+
+		original, err := unmarshalPayload(decrypted)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = original
+	}
+
+	return result, nil
+}
@@ -0,0 +1,321 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// PIITag is the struct tag marking the fields FieldEncryptionConverter encrypts: `pii:"true"`
+	PIITag = "pii"
+	// MetadataEncryptedFields marks JSON payloads whose PII fields are encrypted
+	MetadataEncryptedFields = "encrypted-fields"
+
+	// encryptedFieldKey is the only key of the object that replaces an encrypted field value
+	encryptedFieldKey = "$encrypted"
+)
+
+// FieldEncryptionConverter implements converter.PayloadConverter for JSON payloads whose
+// fields tagged `pii:"true"` are encrypted and everything else stays readable. It replaces
+// the JSON payload converter; values without PII fields are plain JSON payloads.
+//
+// An encrypted field becomes {"$encrypted": "<base64 ciphertext>"}. All fields of a payload
+// are encrypted with one data key from the KeyProvider, which is stored wrapped in the
+// payload metadata like with EnvelopeCodec. Data keys are reused across payloads until they
+// are rotated, so conversions do not wait on the KeyProvider. PII in interface{} fields is
+// not detected.
+type FieldEncryptionConverter struct {
+	keys  *dataKeyCache
+	plans sync.Map
+}
+
+// NewFieldEncryptionConverter creates a converter encrypting PII fields with data keys from
+// the provider, rotated every DefaultDataKeyRotation
+func NewFieldEncryptionConverter(provider KeyProvider) *FieldEncryptionConverter {
+	return NewFieldEncryptionConverterWithRotation(provider, DefaultDataKeyRotation)
+}
+
+// NewFieldEncryptionConverterWithRotation creates a converter that replaces its data key once
+// it is older than rotation
+func NewFieldEncryptionConverterWithRotation(provider KeyProvider, rotation time.Duration) *FieldEncryptionConverter {
+	return &FieldEncryptionConverter{
+		keys: newDataKeyCache(provider, rotation),
+	}
+}
+
+// ToPayload converts a value to JSON and encrypts its PII fields
+func (c *FieldEncryptionConverter) ToPayload(value interface{}) (*commonpb.Payload, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", converter.ErrUnableToEncode, err)
+	}
+
+	payload := &commonpb.Payload{
+		Metadata: map[string][]byte{
+			converter.MetadataEncoding: []byte(c.Encoding()),
+		},
+		Data: data,
+	}
+
+	plan := c.planFor(reflect.TypeOf(value))
+	if plan == nil {
+		return payload, nil
+	}
+
+	tree, err := decodeJSONTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", converter.ErrUnableToEncode, err)
+	}
+
+	// The data key is only generated once a field has a value to encrypt
+	var dataKey *DataKey
+	tree, err = plan.apply(tree, func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		if dataKey == nil {
+			key, err := c.keys.dataKey()
+			if err != nil {
+				return nil, err
+			}
+			dataKey = &key
+		}
+
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := sealGCM(dataKey.Plaintext, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{encryptedFieldKey: ciphertext}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt fields: %w", err)
+	}
+	if dataKey == nil {
+		return payload, nil
+	}
+
+	payload.Data, err = json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", converter.ErrUnableToEncode, err)
+	}
+	payload.Metadata[MetadataEncryptedFields] = []byte(PIITag)
+	payload.Metadata[MetadataEncryptionKeyID] = []byte(dataKey.MasterKeyID)
+	payload.Metadata[MetadataEncryptionDataKey] = dataKey.Wrapped
+	return payload, nil
+}
+
+// FromPayload decrypts the encrypted fields of a payload and converts it to the value
+func (c *FieldEncryptionConverter) FromPayload(payload *commonpb.Payload, valuePtr interface{}) error {
+	data := payload.GetData()
+
+	if _, ok := payload.GetMetadata()[MetadataEncryptedFields]; ok {
+		key, err := c.keys.decrypt(string(payload.Metadata[MetadataEncryptionKeyID]), payload.Metadata[MetadataEncryptionDataKey])
+		if err != nil {
+			return fmt.Errorf("failed to decrypt fields: %w", err)
+		}
+
+		tree, err := decodeJSONTree(data)
+		if err != nil {
+			return fmt.Errorf("%w: %v", converter.ErrUnableToDecode, err)
+		}
+		tree, err = decryptFields(tree, key)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt fields: %w", err)
+		}
+		data, err = json.Marshal(tree)
+		if err != nil {
+			return fmt.Errorf("%w: %v", converter.ErrUnableToDecode, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, valuePtr); err != nil {
+		return fmt.Errorf("%w: %v", converter.ErrUnableToDecode, err)
+	}
+	return nil
+}
+
+// ToString returns the JSON of the payload with its PII fields still encrypted
+func (c *FieldEncryptionConverter) ToString(payload *commonpb.Payload) string {
+	return string(payload.GetData())
+}
+
+// Encoding returns MetadataEncodingJSON, the encoding of the converter it replaces
+func (c *FieldEncryptionConverter) Encoding() string {
+	return converter.MetadataEncodingJSON
+}
+
+// planFor returns the PII fields of a type, or nil when it has none
+func (c *FieldEncryptionConverter) planFor(t reflect.Type) *fieldPlan {
+	if t == nil {
+		return nil
+	}
+	if plan, ok := c.plans.Load(t); ok {
+		return plan.(*fieldPlan)
+	}
+	plan := buildFieldPlan(t, map[reflect.Type]bool{})
+	c.plans.Store(t, plan)
+	return plan
+}
+
+// fieldPlan describes where the PII fields of a type are in its JSON
+type fieldPlan struct {
+	// encrypt encrypts the whole value
+	encrypt bool
+	// fields are the struct fields containing PII, by JSON name
+	fields map[string]*fieldPlan
+	// elem applies to every element of a slice, array or map
+	elem *fieldPlan
+}
+
+// buildFieldPlan walks a type for fields tagged with PIITag. Recursive types are only
+// walked once, so PII below their first level is not found.
+func buildFieldPlan(t reflect.Type, visiting map[reflect.Type]bool) *fieldPlan {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if elem := buildFieldPlan(t.Elem(), visiting); elem != nil {
+			return &fieldPlan{elem: elem}
+		}
+	case reflect.Struct:
+		fields := map[string]*fieldPlan{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+
+			if pii, _ := strconv.ParseBool(field.Tag.Get(PIITag)); pii {
+				if name == "" {
+					name = field.Name
+				}
+				fields[name] = &fieldPlan{encrypt: true}
+				continue
+			}
+
+			child := buildFieldPlan(field.Type, visiting)
+			switch {
+			case child == nil:
+			case field.Anonymous && name == "" && child.fields != nil:
+				// Embedded struct fields are promoted into the parent object
+				for childName, childPlan := range child.fields {
+					fields[childName] = childPlan
+				}
+			default:
+				if name == "" {
+					name = field.Name
+				}
+				fields[name] = child
+			}
+		}
+		if len(fields) > 0 {
+			return &fieldPlan{fields: fields}
+		}
+	}
+	return nil
+}
+
+// apply replaces the PII values in a decoded JSON tree with the result of transform
+func (p *fieldPlan) apply(value interface{}, transform func(interface{}) (interface{}, error)) (interface{}, error) {
+	if p.encrypt {
+		return transform(value)
+	}
+
+	var err error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if p.elem != nil {
+			for key, elem := range v {
+				if v[key], err = p.elem.apply(elem, transform); err != nil {
+					return nil, err
+				}
+			}
+			return v, nil
+		}
+		for name, child := range p.fields {
+			if field, ok := v[name]; ok {
+				if v[name], err = child.apply(field, transform); err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		}
+	case []interface{}:
+		if p.elem != nil {
+			for i, elem := range v {
+				if v[i], err = p.elem.apply(elem, transform); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return value, nil
+}
+
+// decryptFields replaces every encrypted field object in a decoded JSON tree with its value
+func decryptFields(value interface{}, key []byte) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if encoded, ok := v[encryptedFieldKey].(string); ok && len(v) == 1 {
+			ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode field: %w", err)
+			}
+			plaintext, err := openGCM(key, ciphertext)
+			if err != nil {
+				return nil, err
+			}
+			return decodeJSONTree(plaintext)
+		}
+		for name, field := range v {
+			if v[name], err = decryptFields(field, key); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			if v[i], err = decryptFields(elem, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// decodeJSONTree decodes JSON keeping numbers as written, so int64 amounts survive re-encoding
+func decodeJSONTree(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
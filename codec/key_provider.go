@@ -0,0 +1,198 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DataKey is a key that encrypts a single payload. Only its wrapped form, encrypted by a
+// master key, is stored with the payload.
+type DataKey struct {
+	Plaintext   []byte
+	Wrapped     []byte
+	MasterKeyID string
+}
+
+// KeyProvider issues data keys wrapped by a master key it holds, the way a KMS does
+type KeyProvider interface {
+	// GenerateDataKey creates a data key wrapped by the active master key
+	GenerateDataKey(ctx context.Context) (DataKey, error)
+	// DecryptDataKey unwraps a data key wrapped by the master key masterKeyID
+	DecryptDataKey(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error)
+}
+
+// KeyringKeyProvider is a KeyProvider whose master keys are a local keyring, loaded from a
+// keyring file or the environment
+type KeyringKeyProvider struct {
+	keyring *Keyring
+}
+
+// NewKeyringKeyProvider creates a key provider wrapping data keys with the keyring's active key
+func NewKeyringKeyProvider(keyring *Keyring) *KeyringKeyProvider {
+	return &KeyringKeyProvider{
+		keyring: keyring,
+	}
+}
+
+// GenerateDataKey creates a random 32-byte data key
+func (p *KeyringKeyProvider) GenerateDataKey(ctx context.Context) (DataKey, error) {
+	plaintext := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return DataKey{}, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	masterKeyID, masterKey := p.keyring.ActiveKey()
+	wrapped, err := sealGCM(masterKey, plaintext)
+	if err != nil {
+		return DataKey{}, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	return DataKey{Plaintext: plaintext, Wrapped: wrapped, MasterKeyID: masterKeyID}, nil
+}
+
+// DecryptDataKey unwraps a data key
+func (p *KeyringKeyProvider) DecryptDataKey(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	masterKey, err := p.keyring.Key(masterKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := openGCM(masterKey, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return plaintext, nil
+}
+
+// generateDataKeyRequest is the body of a KMS generate-data-key request; the KMS wraps the
+// data key with its active master key
+type generateDataKeyRequest struct{}
+
+// decryptRequest is the body of a KMS decrypt request
+type decryptRequest struct {
+	KeyID          string `json:"key_id"`
+	CiphertextBlob []byte `json:"ciphertext_blob"`
+}
+
+// dataKeyResponse is the body of a KMS response; decrypt responses carry no ciphertext
+type dataKeyResponse struct {
+	KeyID          string `json:"key_id,omitempty"`
+	Plaintext      []byte `json:"plaintext"`
+	CiphertextBlob []byte `json:"ciphertext_blob,omitempty"`
+}
+
+// HTTPKeyProvider is a KeyProvider backed by a KMS over HTTP, such as the stand-in served by
+// NewKMSHandler
+type HTTPKeyProvider struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewHTTPKeyProvider creates a key provider for the KMS at endpoint
+func NewHTTPKeyProvider(endpoint string) *HTTPKeyProvider {
+	return &HTTPKeyProvider{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// GenerateDataKey asks the KMS for a data key
+func (p *HTTPKeyProvider) GenerateDataKey(ctx context.Context) (DataKey, error) {
+	var resp dataKeyResponse
+	if err := p.call(ctx, "/generate-data-key", generateDataKeyRequest{}, &resp); err != nil {
+		return DataKey{}, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return DataKey{Plaintext: resp.Plaintext, Wrapped: resp.CiphertextBlob, MasterKeyID: resp.KeyID}, nil
+}
+
+// DecryptDataKey asks the KMS to unwrap a data key
+func (p *HTTPKeyProvider) DecryptDataKey(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	var resp dataKeyResponse
+	if err := p.call(ctx, "/decrypt", decryptRequest{KeyID: masterKeyID, CiphertextBlob: wrapped}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	return resp.Plaintext, nil
+}
+
+// call posts a JSON request to the KMS and decodes the response
+func (p *HTTPKeyProvider) call(ctx context.Context, path string, body, out interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("KMS returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// NewKMSHandler serves a key provider over the HTTP protocol of HTTPKeyProvider. It is a
+// local stand-in for a managed KMS; the master keys never leave the process.
+func NewKMSHandler(provider KeyProvider) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /generate-data-key", func(w http.ResponseWriter, r *http.Request) {
+		var req generateDataKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		dataKey, err := provider.GenerateDataKey(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, dataKeyResponse{KeyID: dataKey.MasterKeyID, Plaintext: dataKey.Plaintext, CiphertextBlob: dataKey.Wrapped})
+	})
+
+	mux.HandleFunc("POST /decrypt", func(w http.ResponseWriter, r *http.Request) {
+		var req decryptRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		plaintext, err := provider.DecryptDataKey(r.Context(), req.KeyID, req.CiphertextBlob)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, dataKeyResponse{KeyID: req.KeyID, Plaintext: plaintext})
+	})
+
+	return mux
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,171 @@
+package codec
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultKeyID identifies a key configured without an ID, and the key of payloads
+	// encrypted before key IDs were written
+	DefaultKeyID = "default"
+)
+
+var (
+	// ErrNoEncryptionKey is returned when no encryption key is configured
+	ErrNoEncryptionKey = errors.New("no encryption key configured")
+	// ErrUnknownKeyID is returned when a payload was encrypted with a key that is not in the keyring
+	ErrUnknownKeyID = errors.New("unknown encryption key id")
+)
+
+// Keyring holds the active encryption key and the retired keys still accepted for decryption
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// NewKeyring creates a keyring that encrypts with the key activeID; every key must be 32 bytes for AES-256
+func NewKeyring(activeID string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeID)
+	}
+
+	k := &Keyring{activeID: activeID, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if id == "" {
+			return nil, fmt.Errorf("key id must not be empty")
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes for AES-256, got %d bytes", id, len(key))
+		}
+		k.keys[id] = key
+	}
+	return k, nil
+}
+
+// ActiveKey returns the ID and key new payloads are encrypted with
+func (k *Keyring) ActiveKey() (string, []byte) {
+	return k.activeID, k.keys[k.activeID]
+}
+
+// Key returns the key with the given ID
+func (k *Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+	return key, nil
+}
+
+// IDs returns the IDs of all keys in the keyring, sorted
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// keyringFile is the JSON layout of a keyring file
+type keyringFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// LoadKeyringFile reads a keyring from a JSON file of hex-encoded keys:
+//
+//	{"active": "2024-06", "keys": {"2024-06": "<hex>", "2024-01": "<hex>"}}
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring file: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring file %s: %w", path, err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := hex.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return NewKeyring(file.Active, keys)
+}
+
+// LoadKeyringEnv builds a keyring from environment variables:
+//   - ENCRYPTION_KEY: hex-encoded active key
+//   - ENCRYPTION_KEY_ID: ID of the active key, DefaultKeyID when unset
+//   - ENCRYPTION_OLD_KEYS: comma-separated id:hex pairs still accepted for decryption
+func LoadKeyringEnv() (*Keyring, error) {
+	encoded := os.Getenv("ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	activeID := os.Getenv("ENCRYPTION_KEY_ID")
+	if activeID == "" {
+		activeID = DefaultKeyID
+	}
+
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	keys := map[string][]byte{activeID: key}
+
+	if oldKeys := os.Getenv("ENCRYPTION_OLD_KEYS"); oldKeys != "" {
+		for _, entry := range strings.Split(oldKeys, ",") {
+			id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok {
+				return nil, fmt.Errorf("old key %q must be formatted as id:hex", entry)
+			}
+			if _, exists := keys[id]; exists {
+				return nil, fmt.Errorf("duplicate key id %q", id)
+			}
+			key, err := hex.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode key %q: %w", id, err)
+			}
+			keys[id] = key
+		}
+	}
+	return NewKeyring(activeID, keys)
+}
+
+// LoadKeyring loads the keyring from the file named by ENCRYPTION_KEYRING_FILE, or from
+// the environment when it is unset. ErrNoEncryptionKey is returned when neither is configured.
+func LoadKeyring() (*Keyring, error) {
+	if path := os.Getenv("ENCRYPTION_KEYRING_FILE"); path != "" {
+		return LoadKeyringFile(path)
+	}
+	return LoadKeyringEnv()
+}
+
+// LoadKeyringDir reads one keyring per namespace from a directory of <namespace>.json keyring files
+func LoadKeyringDir(dir string) (map[string]*Keyring, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keyring files: %w", err)
+	}
+
+	keyrings := make(map[string]*Keyring, len(files))
+	for _, file := range files {
+		keyring, err := LoadKeyringFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("keyring %s: %w", file, err)
+		}
+		keyrings[strings.TrimSuffix(filepath.Base(file), ".json")] = keyring
+	}
+	return keyrings, nil
+}
@@ -0,0 +1,27 @@
+package codec
+
+import (
+	"fmt"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalPayload serializes the whole payload, not just its data, so the metadata of the
+// payload survives the round trip through a codec
+func marshalPayload(payload *commonpb.Payload) ([]byte, error) {
+	data, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize payload: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalPayload restores a payload serialized by marshalPayload
+func unmarshalPayload(data []byte) (*commonpb.Payload, error) {
+	payload := &commonpb.Payload{}
+	if err := proto.Unmarshal(data, payload); err != nil {
+		return nil, fmt.Errorf("failed to deserialize payload: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"temporal-order-system/models"
+)
+
+const (
+	// FakeAuthorizationLimit is the largest amount, in major units of any currency, the fake gateway will authorize
+	FakeAuthorizationLimit = 9999
+)
+
+// FakeGateway is a deterministic in-memory PaymentGateway for tests and local runs
+type FakeGateway struct {
+	// Latency simulates processor response time on every call
+	Latency time.Duration
+
+	mu        sync.Mutex
+	payments  map[string]*Payment
+	order     []string
+	refunded  map[string]models.Money
+	processed map[string]Payment
+	sequence  int
+}
+
+// NewFakeGateway creates an empty FakeGateway
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		payments:  make(map[string]*Payment),
+		refunded:  make(map[string]models.Money),
+		processed: make(map[string]Payment),
+	}
+}
+
+// Authorize places a hold for the requested amount
+func (f *FakeGateway) Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error) {
+	if err := f.wait(ctx); err != nil {
+		return Payment{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if payment, ok := f.replay(req.IdempotencyKey); ok {
+		return payment, nil
+	}
+
+	if !req.Amount.IsPositive() {
+		return Payment{}, fmt.Errorf("%w: invalid payment amount: %s", ErrDeclined, req.Amount)
+	}
+	if cmp, err := req.Amount.Cmp(models.NewMoneyFromMajor(FakeAuthorizationLimit, req.Amount.Currency)); err != nil || cmp > 0 {
+		return Payment{}, fmt.Errorf("%w: payment amount exceeds authorization limit", ErrDeclined)
+	}
+
+	payment := f.record(req.IdempotencyKey, "AUTH", req.OrderID, PaymentStatusAuthorized, req.Amount)
+	return *payment, nil
+}
+
+// Capture settles an authorization and returns the resulting transaction
+func (f *FakeGateway) Capture(ctx context.Context, req CaptureRequest) (Payment, error) {
+	if err := f.wait(ctx); err != nil {
+		return Payment{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if payment, ok := f.replay(req.IdempotencyKey); ok {
+		return payment, nil
+	}
+
+	auth, ok := f.payments[req.AuthorizationID]
+	if !ok {
+		return Payment{}, fmt.Errorf("authorization %q: %w", req.AuthorizationID, ErrNotFound)
+	}
+	if auth.Status != PaymentStatusAuthorized {
+		return Payment{}, fmt.Errorf("%w: authorization %s is %s", ErrDeclined, auth.ID, auth.Status)
+	}
+	if cmp, err := req.Amount.Cmp(auth.Amount); err != nil {
+		return Payment{}, fmt.Errorf("%w: %v", ErrDeclined, err)
+	} else if cmp > 0 {
+		return Payment{}, fmt.Errorf("%w: capture amount %s exceeds authorized %s", ErrDeclined, req.Amount, auth.Amount)
+	}
+
+	auth.Status = PaymentStatusCaptured
+	payment := f.record(req.IdempotencyKey, "TXN", auth.OrderID, PaymentStatusCaptured, req.Amount)
+	return *payment, nil
+}
+
+// Void releases an authorization that has not been captured
+func (f *FakeGateway) Void(ctx context.Context, req VoidRequest) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.replay(req.IdempotencyKey); ok {
+		return nil
+	}
+
+	auth, ok := f.payments[req.AuthorizationID]
+	if !ok {
+		return fmt.Errorf("authorization %q: %w", req.AuthorizationID, ErrNotFound)
+	}
+	switch auth.Status {
+	case PaymentStatusVoided:
+		return nil
+	case PaymentStatusAuthorized:
+		auth.Status = PaymentStatusVoided
+		f.remember(req.IdempotencyKey, *auth)
+		return nil
+	default:
+		return fmt.Errorf("%w: authorization %s is %s", ErrDeclined, auth.ID, auth.Status)
+	}
+}
+
+// Refund returns some or all of a captured transaction
+func (f *FakeGateway) Refund(ctx context.Context, req RefundRequest) (Payment, error) {
+	if err := f.wait(ctx); err != nil {
+		return Payment{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if payment, ok := f.replay(req.IdempotencyKey); ok {
+		return payment, nil
+	}
+
+	txn, ok := f.payments[req.TransactionID]
+	if !ok {
+		return Payment{}, fmt.Errorf("transaction %q: %w", req.TransactionID, ErrNotFound)
+	}
+	if !req.Amount.IsPositive() {
+		return Payment{}, fmt.Errorf("%w: invalid refund amount: %s", ErrDeclined, req.Amount)
+	}
+	remaining, err := txn.Amount.Sub(f.refunded[txn.ID])
+	if err != nil {
+		return Payment{}, fmt.Errorf("%w: %v", ErrDeclined, err)
+	}
+	if cmp, err := req.Amount.Cmp(remaining); err != nil {
+		return Payment{}, fmt.Errorf("%w: %v", ErrDeclined, err)
+	} else if cmp > 0 {
+		return Payment{}, fmt.Errorf("%w: refund of %s exceeds remaining %s", ErrDeclined, req.Amount, remaining)
+	}
+
+	f.refunded[txn.ID], _ = f.refunded[txn.ID].Add(req.Amount)
+	if f.refunded[txn.ID].Equal(txn.Amount) {
+		txn.Status = PaymentStatusRefunded
+	}
+	payment := f.record(req.IdempotencyKey, "REFUND", txn.OrderID, PaymentStatusRefunded, req.Amount)
+	return *payment, nil
+}
+
+// Status looks up any payment by its gateway ID
+func (f *FakeGateway) Status(ctx context.Context, paymentID string) (Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, ok := f.payments[paymentID]
+	if !ok {
+		return Payment{}, fmt.Errorf("payment %q: %w", paymentID, ErrNotFound)
+	}
+	return *payment, nil
+}
+
+// Payments returns a snapshot of every payment the gateway has created, oldest first
+func (f *FakeGateway) Payments() []Payment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payments := make([]Payment, len(f.order))
+	for i, id := range f.order {
+		payments[i] = *f.payments[id]
+	}
+	return payments
+}
+
+// record stores a new payment with the next deterministic ID. Callers must hold f.mu.
+func (f *FakeGateway) record(idempotencyKey, prefix, orderID string, status PaymentStatus, amount models.Money) *Payment {
+	f.sequence++
+	payment := &Payment{
+		ID:      fmt.Sprintf("%s-%s-%d", prefix, shortID(orderID), f.sequence),
+		OrderID: orderID,
+		Status:  status,
+		Amount:  amount,
+	}
+	f.payments[payment.ID] = payment
+	f.order = append(f.order, payment.ID)
+	f.remember(idempotencyKey, *payment)
+	return payment
+}
+
+// remember stores the result of a processed request. Callers must hold f.mu.
+func (f *FakeGateway) remember(idempotencyKey string, payment Payment) {
+	if idempotencyKey != "" {
+		f.processed[idempotencyKey] = payment
+	}
+}
+
+// replay returns the original result for an already processed request. Callers must hold f.mu.
+func (f *FakeGateway) replay(idempotencyKey string) (Payment, bool) {
+	if idempotencyKey == "" {
+		return Payment{}, false
+	}
+	payment, ok := f.processed[idempotencyKey]
+	return payment, ok
+}
+
+// wait simulates processor latency while honouring cancellation
+func (f *FakeGateway) wait(ctx context.Context) error {
+	if f.Latency <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-time.After(f.Latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shortID returns at most the first 8 characters of an ID
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
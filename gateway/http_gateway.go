@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// IdempotencyKeyHeader carries the request's idempotency key to the processor
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// HTTPGateway is a PaymentGateway backed by a JSON-over-HTTP payment processor
+type HTTPGateway struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewHTTPGateway creates a new HTTPGateway for the processor at baseURL
+func NewHTTPGateway(baseURL string) *HTTPGateway {
+	return &HTTPGateway{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: baseURL,
+	}
+}
+
+// Authorize places a hold for the requested amount
+func (g *HTTPGateway) Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error) {
+	var payment Payment
+	err := g.do(ctx, http.MethodPost, "/payments/authorize", req.IdempotencyKey, req, &payment)
+	return payment, err
+}
+
+// Capture settles an authorization and returns the resulting transaction
+func (g *HTTPGateway) Capture(ctx context.Context, req CaptureRequest) (Payment, error) {
+	var payment Payment
+	err := g.do(ctx, http.MethodPost, "/payments/capture", req.IdempotencyKey, req, &payment)
+	return payment, err
+}
+
+// Void releases an authorization that has not been captured
+func (g *HTTPGateway) Void(ctx context.Context, req VoidRequest) error {
+	return g.do(ctx, http.MethodPost, "/payments/void", req.IdempotencyKey, req, nil)
+}
+
+// Refund returns some or all of a captured transaction
+func (g *HTTPGateway) Refund(ctx context.Context, req RefundRequest) (Payment, error) {
+	var payment Payment
+	err := g.do(ctx, http.MethodPost, "/payments/refund", req.IdempotencyKey, req, &payment)
+	return payment, err
+}
+
+// Status looks up any payment by its gateway ID
+func (g *HTTPGateway) Status(ctx context.Context, paymentID string) (Payment, error) {
+	var payment Payment
+	err := g.do(ctx, http.MethodGet, "/payments/"+url.PathEscape(paymentID), "", nil, &payment)
+	return payment, err
+}
+
+// do sends a JSON request to the processor and decodes the JSON response into out
+func (g *HTTPGateway) do(ctx context.Context, method, path, idempotencyKey string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		jsonData, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal gateway request: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create gateway request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call payment gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s %s: %w", method, path, ErrNotFound)
+	case resp.StatusCode == http.StatusPaymentRequired || resp.StatusCode == http.StatusUnprocessableEntity:
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s", ErrDeclined, string(respBody))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("payment gateway returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode gateway response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"temporal-order-system/models"
+)
+
+var (
+	// ErrNotFound is returned when the gateway has no record of a payment
+	ErrNotFound = errors.New("payment not found")
+	// ErrDeclined is returned when the gateway refuses an operation
+	ErrDeclined = errors.New("payment declined")
+)
+
+// PaymentStatus represents the lifecycle state of a payment at the gateway
+type PaymentStatus string
+
+const (
+	PaymentStatusAuthorized PaymentStatus = "AUTHORIZED"
+	PaymentStatusCaptured   PaymentStatus = "CAPTURED"
+	PaymentStatusVoided     PaymentStatus = "VOIDED"
+	PaymentStatusRefunded   PaymentStatus = "REFUNDED"
+)
+
+// AuthorizeRequest asks the gateway to place a hold on the customer's funds
+type AuthorizeRequest struct {
+	IdempotencyKey string       `json:"-"`
+	OrderID        string       `json:"order_id"`
+	Amount         models.Money `json:"amount"`
+}
+
+// CaptureRequest asks the gateway to settle a previous authorization
+type CaptureRequest struct {
+	IdempotencyKey  string       `json:"-"`
+	OrderID         string       `json:"order_id"`
+	AuthorizationID string       `json:"authorization_id"`
+	Amount          models.Money `json:"amount"`
+}
+
+// VoidRequest asks the gateway to release an uncaptured authorization
+type VoidRequest struct {
+	IdempotencyKey  string `json:"-"`
+	AuthorizationID string `json:"authorization_id"`
+}
+
+// RefundRequest asks the gateway to return captured funds
+type RefundRequest struct {
+	IdempotencyKey string       `json:"-"`
+	TransactionID  string       `json:"transaction_id"`
+	Amount         models.Money `json:"amount"`
+}
+
+// Payment is the gateway's record of an authorization, capture or refund
+type Payment struct {
+	ID      string        `json:"id"`
+	OrderID string        `json:"order_id"`
+	Status  PaymentStatus `json:"status"`
+	Amount  models.Money  `json:"amount"`
+}
+
+// PaymentGateway is the interface to an external payment processor.
+// A request whose IdempotencyKey has already been processed must return the
+// original result instead of repeating the effect.
+type PaymentGateway interface {
+	// Authorize places a hold for the requested amount
+	Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error)
+	// Capture settles an authorization and returns the resulting transaction
+	Capture(ctx context.Context, req CaptureRequest) (Payment, error)
+	// Void releases an authorization that has not been captured
+	Void(ctx context.Context, req VoidRequest) error
+	// Refund returns some or all of a captured transaction
+	Refund(ctx context.Context, req RefundRequest) (Payment, error)
+	// Status looks up any payment by its gateway ID
+	Status(ctx context.Context, paymentID string) (Payment, error)
+}
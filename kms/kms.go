@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/logging"
+)
+
+func main() {
+	// Log through slog as configured by LOG_LEVEL and LOG_FORMAT, JSON at info by default
+	logOptions, err := logging.OptionsFromEnv(logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON})
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	slog.SetDefault(logging.NewSlogLogger(os.Stderr, logOptions))
+
+	// Get listen address from environment or use default
+	listenAddress := os.Getenv("KMS_ADDRESS")
+	if listenAddress == "" {
+		listenAddress = ":8095"
+	}
+
+	// The master keys are a keyring from ENCRYPTION_KEYRING_FILE or ENCRYPTION_KEY; they never leave this process
+	keyring, err := codec.LoadKeyring()
+	if err != nil {
+		fatal("Failed to load master keyring", err)
+	}
+
+	activeKeyID, _ := keyring.ActiveKey()
+	slog.Info("Starting local KMS stand-in", "listen", listenAddress, "master_key", activeKeyID, "keys", keyring.IDs())
+
+	if err := http.ListenAndServe(listenAddress, codec.NewKMSHandler(codec.NewKeyringKeyProvider(keyring))); err != nil {
+		fatal("KMS stand-in failed", err)
+	}
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"context"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Correlation fields added to every workflow and activity log line
+const (
+	FieldOrderID    = "order_id"
+	FieldWorkflowID = "workflow_id"
+	FieldRunID      = "run_id"
+	FieldAttempt    = "attempt"
+	FieldTaskQueue  = "task_queue"
+)
+
+// orderIDKey holds the order ID of an activity on its context
+type orderIDKey struct{}
+
+// NewInterceptor returns the worker interceptor adding the correlation fields to the loggers of
+// workflows and activities. The order ID is taken from the models.Order argument of the
+// workflow or activity, else from the order or payment workflow ID, and left out when neither
+// has one.
+func NewInterceptor() interceptor.WorkerInterceptor {
+	return &workerInterceptor{}
+}
+
+type workerInterceptor struct {
+	interceptor.WorkerInterceptorBase
+}
+
+func (w *workerInterceptor) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	i := &workflowInboundInterceptor{}
+	i.Next = next
+	return i
+}
+
+func (w *workerInterceptor) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	i := &activityInboundInterceptor{}
+	i.Next = next
+	return i
+}
+
+// workflowInboundInterceptor records the order ID of a workflow execution for the loggers of
+// the workflow and its signal, query and update handlers
+type workflowInboundInterceptor struct {
+	interceptor.WorkflowInboundInterceptorBase
+	orderID string
+}
+
+func (i *workflowInboundInterceptor) Init(outbound interceptor.WorkflowOutboundInterceptor) error {
+	o := &workflowOutboundInterceptor{inbound: i}
+	o.Next = outbound
+	return i.Next.Init(o)
+}
+
+func (i *workflowInboundInterceptor) ExecuteWorkflow(ctx workflow.Context, in *interceptor.ExecuteWorkflowInput) (interface{}, error) {
+	i.orderID = orderID(in.Args, workflow.GetInfo(ctx).WorkflowExecution.ID)
+	return i.Next.ExecuteWorkflow(ctx, in)
+}
+
+type workflowOutboundInterceptor struct {
+	interceptor.WorkflowOutboundInterceptorBase
+	inbound *workflowInboundInterceptor
+}
+
+func (o *workflowOutboundInterceptor) GetLogger(ctx workflow.Context) log.Logger {
+	info := workflow.GetInfo(ctx)
+	return withFields(o.Next.GetLogger(ctx), o.inbound.orderID, info.WorkflowExecution.ID, info.WorkflowExecution.RunID, info.Attempt, info.TaskQueueName)
+}
+
+// activityInboundInterceptor puts the order ID of an activity on its context, so activities
+// without an order argument, such as RefundPayment, log it too
+type activityInboundInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+}
+
+func (i *activityInboundInterceptor) Init(outbound interceptor.ActivityOutboundInterceptor) error {
+	o := &activityOutboundInterceptor{}
+	o.Next = outbound
+	return i.Next.Init(o)
+}
+
+func (i *activityInboundInterceptor) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	if id := orderID(in.Args, activity.GetInfo(ctx).WorkflowExecution.ID); id != "" {
+		ctx = context.WithValue(ctx, orderIDKey{}, id)
+	}
+	return i.Next.ExecuteActivity(ctx, in)
+}
+
+type activityOutboundInterceptor struct {
+	interceptor.ActivityOutboundInterceptorBase
+}
+
+func (o *activityOutboundInterceptor) GetLogger(ctx context.Context) log.Logger {
+	info := activity.GetInfo(ctx)
+	id, _ := ctx.Value(orderIDKey{}).(string)
+	return withFields(o.Next.GetLogger(ctx), id, info.WorkflowExecution.ID, info.WorkflowExecution.RunID, info.Attempt, info.TaskQueue)
+}
+
+// withFields adds the correlation fields to logger
+func withFields(logger log.Logger, orderID, workflowID, runID string, attempt int32, taskQueue string) log.Logger {
+	fields := []interface{}{FieldWorkflowID, workflowID, FieldRunID, runID, FieldAttempt, attempt, FieldTaskQueue, taskQueue}
+	if orderID != "" {
+		fields = append([]interface{}{FieldOrderID, orderID}, fields...)
+	}
+	return log.With(logger, fields...)
+}
+
+// orderID returns the ID of the first order in args, or the order ID in workflowID
+func orderID(args []interface{}, workflowID string) string {
+	for _, arg := range args {
+		switch order := arg.(type) {
+		case models.Order:
+			return order.ID
+		case *models.Order:
+			if order != nil {
+				return order.ID
+			}
+		}
+	}
+	return workflows.OrderIDFromWorkflowID(workflowID)
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Output formats selectable with LOG_FORMAT
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Options configure the slog handler of a process
+type Options struct {
+	Level  slog.Level
+	Format string
+}
+
+// OptionsFromEnv reads LOG_LEVEL (debug, info, warn or error) and LOG_FORMAT (json or text),
+// falling back to defaults
+func OptionsFromEnv(defaults Options) (Options, error) {
+	opts := defaults
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := opts.Level.UnmarshalText([]byte(value)); err != nil {
+			return Options{}, fmt.Errorf("invalid LOG_LEVEL %q: use debug, info, warn or error", value)
+		}
+	}
+	if value := os.Getenv("LOG_FORMAT"); value != "" {
+		if value != FormatJSON && value != FormatText {
+			return Options{}, fmt.Errorf("invalid LOG_FORMAT %q: use %s or %s", value, FormatJSON, FormatText)
+		}
+		opts.Format = value
+	}
+	return opts, nil
+}
+
+// NewSlogLogger creates a slog logger writing to w, for the SDK through
+// log.NewStructuredLogger. Keys are snake_case, including the CamelCase tags of the Temporal
+// SDK, and a key is written once even when added twice.
+func NewSlogLogger(w io.Writer, opts Options) *slog.Logger {
+	handlerOptions := &slog.HandlerOptions{Level: opts.Level}
+	var handler slog.Handler
+	if opts.Format == FormatText {
+		handler = slog.NewTextHandler(w, handlerOptions)
+	} else {
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	}
+	return slog.New(&keyHandler{next: handler, seen: map[string]bool{}})
+}
+
+// keyHandler renames keys to snake_case and drops attributes whose key was already added with
+// WithAttrs, so the correlation fields of NewInterceptor do not repeat the SDK's tags
+type keyHandler struct {
+	next slog.Handler
+	seen map[string]bool
+}
+
+func (h *keyHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *keyHandler) Handle(ctx context.Context, record slog.Record) error {
+	renamed := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		attr.Key = snakeCase(attr.Key)
+		if !h.seen[attr.Key] {
+			renamed.AddAttrs(attr)
+		}
+		return true
+	})
+	return h.next.Handle(ctx, renamed)
+}
+
+func (h *keyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	seen := make(map[string]bool, len(h.seen)+len(attrs))
+	for k := range h.seen {
+		seen[k] = true
+	}
+	added := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Key = snakeCase(attr.Key)
+		if seen[attr.Key] {
+			continue
+		}
+		seen[attr.Key] = true
+		added = append(added, attr)
+	}
+	return &keyHandler{next: h.next.WithAttrs(added), seen: seen}
+}
+
+func (h *keyHandler) WithGroup(name string) slog.Handler {
+	return &keyHandler{next: h.next.WithGroup(name), seen: map[string]bool{}}
+}
+
+// snakeCase converts a CamelCase key such as WorkflowID to workflow_id; other keys are unchanged
+func snakeCase(key string) string {
+	if key == "" || !unicode.IsUpper(rune(key[0])) {
+		return key
+	}
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A new word starts at an upper case letter after a lower case one, or at the
+			// last upper case letter of an acronym followed by a lower case one
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
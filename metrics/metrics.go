@@ -0,0 +1,44 @@
+package metrics
+
+// Business metrics of the order system, emitted alongside the SDK's temporal_* metrics.
+// Workflows emit through workflow.GetMetricsHandler and activities through
+// activity.GetMetricsHandler, so they carry the workflow or activity type and task queue tags.
+const (
+	// OrdersStarted counts order workflows that started
+	OrdersStarted = "orders_started"
+	// OrdersCompleted counts orders that completed
+	OrdersCompleted = "orders_completed"
+	// OrdersFailed counts failed orders by TagReason: validation, payment or processing
+	OrdersFailed = "orders_failed"
+	// OrdersCancelled counts cancelled orders by TagReason, the step they were cancelled
+	// after: received, validation or payment
+	OrdersCancelled = "orders_cancelled"
+
+	// ValidationRejections counts orders the validation service declared invalid
+	ValidationRejections = "order_validation_rejections"
+
+	// PaymentAuthorizeLatency and PaymentCaptureLatency time the payment gateway calls, by TagOutcome
+	PaymentAuthorizeLatency = "payment_authorize_latency"
+	PaymentCaptureLatency   = "payment_capture_latency"
+
+	// CompensationRuns counts saga rollbacks by TagOutcome: completed, or incomplete when a
+	// compensation failed
+	CompensationRuns = "compensation_runs"
+	// CompensationFailures counts compensating activities that failed, by TagStep
+	CompensationFailures = "compensation_failures"
+)
+
+// Tags of the business metrics
+const (
+	TagReason  = "reason"
+	TagOutcome = "outcome"
+	TagStep    = "step"
+)
+
+// Values of TagOutcome
+const (
+	OutcomeSuccess    = "success"
+	OutcomeFailure    = "failure"
+	OutcomeCompleted  = "completed"
+	OutcomeIncomplete = "incomplete"
+)
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/uber-go/tally/v4"
+	tallyprom "github.com/uber-go/tally/v4/prometheus"
+	"go.temporal.io/sdk/client"
+	sdktally "go.temporal.io/sdk/contrib/tally"
+)
+
+// DefaultBuckets are the histogram buckets of timers, in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// DefaultReportInterval is how often counters and gauges reach the Prometheus registry
+const DefaultReportInterval = time.Second
+
+// help describes the business metrics by their Prometheus names; other metrics get the
+// generic help of the tally reporter
+var help = map[string]string{
+	OrdersStarted + "_total":             "Order workflows that started.",
+	OrdersCompleted + "_total":           "Orders that completed.",
+	OrdersFailed + "_total":              "Orders that failed, by reason.",
+	OrdersCancelled + "_total":           "Orders that were cancelled, by the last completed step.",
+	ValidationRejections + "_total":      "Orders the validation service declared invalid.",
+	PaymentAuthorizeLatency + "_seconds": "Latency of payment gateway authorizations, by outcome.",
+	PaymentCaptureLatency + "_seconds":   "Latency of payment gateway captures, by outcome.",
+	CompensationRuns + "_total":          "Saga rollbacks, by outcome.",
+	CompensationFailures + "_total":      "Compensating activities that failed, by step.",
+}
+
+// Registry exports the metrics of a client.MetricsHandler to Prometheus through Temporal's
+// tally handler and the tally Prometheus reporter. Names follow the Prometheus conventions:
+// counters end in _total, and timers become histograms in seconds ending in _seconds.
+type Registry struct {
+	scope   tally.Scope
+	closer  io.Closer
+	handler http.Handler
+}
+
+// NewRegistry creates an empty Registry reporting every DefaultReportInterval
+func NewRegistry() *Registry {
+	return NewRegistryWithInterval(DefaultReportInterval)
+}
+
+// NewRegistryWithInterval creates an empty Registry reporting counters and gauges every interval
+func NewRegistryWithInterval(interval time.Duration) *Registry {
+	reporter := tallyprom.NewReporter(tallyprom.Options{
+		Registerer:              prom.NewRegistry(),
+		DefaultTimerType:        tallyprom.HistogramTimerType,
+		DefaultHistogramBuckets: DefaultBuckets,
+		// Prometheus needs the same tags on every series of a name; a series with other tags
+		// is dropped rather than crashing the worker
+		OnRegisterError: func(err error) {
+			slog.Warn("Dropped metric", "error", err)
+		},
+	})
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		CachedReporter:         describedReporter{Reporter: reporter},
+		Separator:              tallyprom.DefaultSeparator,
+		SanitizeOptions:        &sdktally.PrometheusSanitizeOptions,
+		OmitCardinalityMetrics: true,
+	}, interval)
+	return &Registry{
+		scope:   sdktally.NewPrometheusNamingScope(scope),
+		closer:  closer,
+		handler: reporter.HTTPHandler(),
+	}
+}
+
+// Handler returns the metrics handler to set as client.Options.MetricsHandler
+func (r *Registry) Handler() client.MetricsHandler {
+	return sdktally.NewMetricsHandler(r.scope)
+}
+
+// ServeHTTP writes every metric in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+// Close reports the pending counters and gauges and stops reporting
+func (r *Registry) Close() error {
+	return r.closer.Close()
+}
+
+// describedReporter registers the business metrics with their help before the tally
+// Prometheus reporter allocates them
+type describedReporter struct {
+	tallyprom.Reporter
+}
+
+func (r describedReporter) AllocateCounter(name string, tags map[string]string) tally.CachedCount {
+	if h, ok := help[name]; ok {
+		// A clash is reported to OnRegisterError by the allocation
+		_, _ = r.RegisterCounter(name, tagKeys(tags), h)
+	}
+	return r.Reporter.AllocateCounter(name, tags)
+}
+
+func (r describedReporter) AllocateTimer(name string, tags map[string]string) tally.CachedTimer {
+	if h, ok := help[name]; ok {
+		// A clash is reported to OnRegisterError by the allocation
+		_, _ = r.RegisterTimer(name, tagKeys(tags), h, nil)
+	}
+	return r.Reporter.AllocateTimer(name, tags)
+}
+
+// tagKeys returns the keys of tags
+func tagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	return keys
+}
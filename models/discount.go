@@ -0,0 +1,14 @@
+package models
+
+// discountCodes maps each supported discount code to its percentage off the subtotal
+var discountCodes = map[string]int{
+	"SAVE10":   10,
+	"SAVE20":   20,
+	"WELCOME5": 5,
+}
+
+// DiscountPercent returns the percentage off for a discount code
+func DiscountPercent(code string) (int, bool) {
+	percent, ok := discountCodes[code]
+	return percent, ok
+}
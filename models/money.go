@@ -0,0 +1,261 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultCurrency is assumed for legacy payloads that carry a bare number
+	DefaultCurrency = "USD"
+)
+
+// ErrOverflow is returned when an amount does not fit in int64 minor units
+var ErrOverflow = errors.New("amount out of range")
+
+// currencyExponents lists ISO-4217 currencies whose minor unit is not 1/100
+var currencyExponents = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "OMR": 3, "TND": 3, "UGX": 0, "VND": 0,
+}
+
+// Money is an exact monetary amount in the minor units (e.g. cents) of an ISO-4217 currency
+type Money struct {
+	MinorUnits int64  `json:"minor_units"`
+	Currency   string `json:"currency"`
+}
+
+// NewMoney creates a Money value from minor units
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{MinorUnits: minorUnits, Currency: currency}
+}
+
+// NewMoneyFromMajor creates a Money value from whole major units (e.g. dollars)
+func NewMoneyFromMajor(majorUnits int64, currency string) Money {
+	return Money{MinorUnits: majorUnits * scale(currency), Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "333.33" in the given currency: an optional
+// leading "-", digits and an optional fraction of digits. More fractional digits than the
+// currency allows is an error rather than a rounding.
+func ParseMoney(value, currency string) (Money, error) {
+	if err := validateCurrency(currency); err != nil {
+		return Money{}, err
+	}
+
+	value = strings.TrimSpace(value)
+	digits := strings.TrimPrefix(value, "-")
+	negative := len(digits) < len(value)
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	exponent := Exponent(currency)
+	if whole == "" || !isDigits(whole) || !isDigits(frac) || len(frac) > exponent {
+		return Money{}, fmt.Errorf("invalid %s amount %q", currency, value)
+	}
+	frac += strings.Repeat("0", exponent-len(frac))
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		// Only digits are left, so the whole part is too large
+		return Money{}, fmt.Errorf("invalid %s amount %q: %w", currency, value, ErrOverflow)
+	}
+	var minor int64
+	if frac != "" {
+		minor, err = strconv.ParseInt(frac, 10, 64)
+		if err != nil {
+			return Money{}, fmt.Errorf("invalid %s amount %q", currency, value)
+		}
+	}
+
+	if major > (math.MaxInt64-minor)/scale(currency) {
+		return Money{}, fmt.Errorf("invalid %s amount %q: %w", currency, value, ErrOverflow)
+	}
+	units := major*scale(currency) + minor
+	if negative {
+		units = -units
+	}
+	return Money{MinorUnits: units, Currency: currency}, nil
+}
+
+// isDigits reports whether s consists of ASCII digits only; the empty string does
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Exponent returns the number of minor-unit digits of a currency
+func Exponent(currency string) int {
+	if exp, ok := currencyExponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// scale returns how many minor units make up one major unit of a currency
+func scale(currency string) int64 {
+	s := int64(1)
+	for i := 0; i < Exponent(currency); i++ {
+		s *= 10
+	}
+	return s
+}
+
+// validateCurrency checks that a currency looks like an ISO-4217 code
+func validateCurrency(currency string) error {
+	if len(currency) != 3 || strings.ToUpper(currency) != currency {
+		return fmt.Errorf("invalid ISO-4217 currency code %q", currency)
+	}
+	return nil
+}
+
+// Add returns m + o. Both amounts must share a currency; a zero amount adopts the other's currency.
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.commonCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	units := m.MinorUnits + o.MinorUnits
+	if (o.MinorUnits > 0 && units < m.MinorUnits) || (o.MinorUnits < 0 && units > m.MinorUnits) {
+		return Money{}, fmt.Errorf("%s + %s: %w", m, o, ErrOverflow)
+	}
+	return Money{MinorUnits: units, Currency: currency}, nil
+}
+
+// Sub returns m - o. Both amounts must share a currency.
+func (m Money) Sub(o Money) (Money, error) {
+	currency, err := m.commonCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	units := m.MinorUnits - o.MinorUnits
+	if (o.MinorUnits < 0 && units < m.MinorUnits) || (o.MinorUnits > 0 && units > m.MinorUnits) {
+		return Money{}, fmt.Errorf("%s - %s: %w", m, o, ErrOverflow)
+	}
+	return Money{MinorUnits: units, Currency: currency}, nil
+}
+
+// Mul returns m multiplied by a whole quantity
+func (m Money) Mul(quantity int64) (Money, error) {
+	units := m.MinorUnits * quantity
+	if quantity != 0 && (units/quantity != m.MinorUnits || (quantity == -1 && m.MinorUnits == math.MinInt64)) {
+		return Money{}, fmt.Errorf("%s * %d: %w", m, quantity, ErrOverflow)
+	}
+	return Money{MinorUnits: units, Currency: m.Currency}, nil
+}
+
+// Cmp compares m and o, returning -1, 0 or +1. Both amounts must share a currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := m.commonCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.MinorUnits < o.MinorUnits:
+		return -1, nil
+	case m.MinorUnits > o.MinorUnits:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Equal reports whether m and o are the same amount in the same currency
+func (m Money) Equal(o Money) bool {
+	return m.MinorUnits == o.MinorUnits && m.Currency == o.Currency
+}
+
+// IsPositive reports whether m is greater than zero
+func (m Money) IsPositive() bool {
+	return m.MinorUnits > 0
+}
+
+// IsZero reports whether m is zero
+func (m Money) IsZero() bool {
+	return m.MinorUnits == 0
+}
+
+// Decimal formats m as a plain decimal string such as "333.33"
+func (m Money) Decimal() string {
+	exponent := Exponent(m.Currency)
+	units := m.MinorUnits
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d", sign, units)
+	}
+	s := scale(m.Currency)
+	return fmt.Sprintf("%s%d.%0*d", sign, units/s, exponent, units%s)
+}
+
+// String formats m with its currency, e.g. "333.33 USD"
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Decimal(), m.Currency)
+}
+
+// commonCurrency returns the currency shared by m and o
+func (m Money) commonCurrency(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case o.MinorUnits == 0:
+		return m.Currency, nil
+	case m.MinorUnits == 0:
+		return o.Currency, nil
+	default:
+		return "", fmt.Errorf("currency mismatch: %s and %s", m.Currency, o.Currency)
+	}
+}
+
+// UnmarshalJSON accepts the {"minor_units", "currency"} object as well as the legacy
+// bare number in major units, which is assumed to be in DefaultCurrency. Other fields in the
+// object are rejected, so a misspelled currency does not silently default.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] != '{' {
+		var major float64
+		if err := json.Unmarshal(data, &major); err != nil {
+			return fmt.Errorf("invalid money value %s: %w", string(data), err)
+		}
+		*m = Money{
+			MinorUnits: int64(math.Round(major * float64(scale(DefaultCurrency)))),
+			Currency:   DefaultCurrency,
+		}
+		return nil
+	}
+
+	// Decode through an alias so the object form does not recurse into UnmarshalJSON
+	type money Money
+	var v money
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&v); err != nil {
+		return fmt.Errorf("invalid money value %s: %w", string(data), err)
+	}
+	switch {
+	case v.Currency == "" && v.MinorUnits == 0:
+		// The zero value round-trips unchanged
+	case v.Currency == "":
+		v.Currency = DefaultCurrency
+	default:
+		if err := validateCurrency(v.Currency); err != nil {
+			return err
+		}
+	}
+	*m = Money(v)
+	return nil
+}
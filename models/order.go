@@ -1,23 +1,209 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
 
-// Order represents an order in the system
+	"github.com/google/uuid"
+)
+
+// Order represents an order in the system. Fields tagged `pii:"true"` are encrypted on
+// their own when field-level encryption is enabled, see codec.FieldEncryptionConverter.
 type Order struct {
-	ID        string      `json:"id"`
-	Items     []OrderItem `json:"items"`
-	Amount    float64     `json:"amount"`
-	Status    OrderStatus `json:"status"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	ID              string      `json:"id"`
+	CustomerName    string      `json:"customer_name,omitempty" pii:"true"`
+	CustomerEmail   string      `json:"customer_email,omitempty" pii:"true"`
+	CardToken       string      `json:"card_token,omitempty" pii:"true"`
+	Items           []OrderItem `json:"items"`
+	Amount          Money       `json:"amount"`
+	DiscountCode    string      `json:"discount_code,omitempty"`
+	Discount        Money       `json:"discount"`
+	ShippingAddress *Address    `json:"shipping_address,omitempty"`
+	Status          OrderStatus `json:"status"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// Address represents a shipping address. State and country stay readable with field-level
+// encryption; the rest identifies the customer.
+type Address struct {
+	Line1      string `json:"line1" pii:"true"`
+	Line2      string `json:"line2,omitempty" pii:"true"`
+	City       string `json:"city" pii:"true"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postal_code" pii:"true"`
+	Country    string `json:"country"`
+}
+
+// Validate checks that the address has every required field
+func (a Address) Validate() error {
+	switch {
+	case a.Line1 == "":
+		return fmt.Errorf("address line1 is required")
+	case a.City == "":
+		return fmt.Errorf("address city is required")
+	case a.PostalCode == "":
+		return fmt.Errorf("address postal code is required")
+	case len(a.Country) != 2:
+		return fmt.Errorf("address country must be an ISO-3166 alpha-2 code")
+	}
+	return nil
 }
 
 // OrderItem represents a single item in an order
 type OrderItem struct {
-	ProductID string  `json:"product_id"`
-	Name      string  `json:"name"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID string `json:"product_id"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	Price     Money  `json:"price"`
+}
+
+// Subtotal sums price times quantity over all items in the order's currency
+func (o Order) Subtotal() (Money, error) {
+	subtotal := NewMoney(0, o.Amount.Currency)
+	for _, item := range o.Items {
+		line, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return Money{}, fmt.Errorf("item %s: %w", item.ProductID, err)
+		}
+		subtotal, err = subtotal.Add(line)
+		if err != nil {
+			return Money{}, fmt.Errorf("item %s: %w", item.ProductID, err)
+		}
+	}
+	return subtotal, nil
+}
+
+// Total is the subtotal less any discount
+func (o Order) Total() (Money, error) {
+	subtotal, err := o.Subtotal()
+	if err != nil {
+		return Money{}, err
+	}
+	total, err := subtotal.Sub(o.Discount)
+	if err != nil {
+		return Money{}, fmt.Errorf("discount: %w", err)
+	}
+	return total, nil
+}
+
+// Reprice recomputes the discount and the amount from the current items and discount code
+func (o *Order) Reprice() error {
+	subtotal, err := o.Subtotal()
+	if err != nil {
+		return err
+	}
+
+	o.Discount = NewMoney(0, subtotal.Currency)
+	if o.DiscountCode != "" {
+		percent, ok := DiscountPercent(o.DiscountCode)
+		if !ok {
+			return fmt.Errorf("unknown discount code %q", o.DiscountCode)
+		}
+		o.Discount = NewMoney(subtotal.MinorUnits*int64(percent)/100, subtotal.Currency)
+	}
+
+	o.Amount, err = subtotal.Sub(o.Discount)
+	return err
+}
+
+// Clone returns a copy of the order that shares no slices or pointers with the original
+func (o Order) Clone() Order {
+	clone := o
+	clone.Items = append([]OrderItem(nil), o.Items...)
+	if o.ShippingAddress != nil {
+		address := *o.ShippingAddress
+		clone.ShippingAddress = &address
+	}
+	return clone
+}
+
+// Validate checks that the order can be submitted: it has an ID and items with unique
+// product IDs, every price and the amount share one currency, and the amount is positive and
+// equal to the item total less the discount, which ProcessOrder checks again.
+func (o Order) Validate() error {
+	if o.ID == "" {
+		return fmt.Errorf("order ID is required")
+	}
+	if len(o.Items) == 0 {
+		return fmt.Errorf("order %s has no items", o.ID)
+	}
+
+	seen := make(map[string]bool, len(o.Items))
+	for i, item := range o.Items {
+		switch {
+		case item.ProductID == "":
+			return fmt.Errorf("order %s item %d: product ID is required", o.ID, i+1)
+		case seen[item.ProductID]:
+			return fmt.Errorf("order %s item %s: duplicate product ID", o.ID, item.ProductID)
+		case item.Quantity <= 0:
+			return fmt.Errorf("order %s item %s: quantity must be positive, got %d", o.ID, item.ProductID, item.Quantity)
+		case item.Price.MinorUnits < 0:
+			return fmt.Errorf("order %s item %s: price must not be negative, got %s", o.ID, item.ProductID, item.Price)
+		case item.Price.Currency != o.Amount.Currency && o.Amount.Currency != "":
+			return fmt.Errorf("order %s item %s: price currency %s does not match order currency %s", o.ID, item.ProductID, item.Price.Currency, o.Amount.Currency)
+		}
+		seen[item.ProductID] = true
+	}
+
+	if err := validateCurrency(o.Amount.Currency); err != nil {
+		return fmt.Errorf("order %s amount: %w", o.ID, err)
+	}
+	if !o.Amount.IsPositive() {
+		return fmt.Errorf("order %s amount must be positive, got %s", o.ID, o.Amount)
+	}
+	total, err := o.Total()
+	if err != nil {
+		return fmt.Errorf("order %s: %w", o.ID, err)
+	}
+	if !total.Equal(o.Amount) {
+		return fmt.Errorf("order %s amount %s does not match its item total %s", o.ID, o.Amount, total)
+	}
+
+	if o.DiscountCode != "" {
+		if _, ok := DiscountPercent(o.DiscountCode); !ok {
+			return fmt.Errorf("order %s: unknown discount code %q", o.ID, o.DiscountCode)
+		}
+	}
+	if o.ShippingAddress != nil {
+		if err := o.ShippingAddress.Validate(); err != nil {
+			return fmt.Errorf("order %s: %w", o.ID, err)
+		}
+	}
+	return nil
+}
+
+// Prepare readies a new order for submission. It generates a missing ID, computes the
+// discount and, unless the order already has one, the amount from the items, sets the
+// pending status and timestamps, and validates the order. An amount the order already has
+// must match the computed one.
+func (o *Order) Prepare(now time.Time) error {
+	if o.ID == "" {
+		o.ID = uuid.New().String()
+	}
+
+	amount := o.Amount
+	if err := o.Reprice(); err != nil {
+		return fmt.Errorf("order %s: %w", o.ID, err)
+	}
+	if !amount.IsZero() {
+		o.Amount = amount
+	}
+
+	o.Status = OrderStatusPending
+	o.CreatedAt = now
+	o.UpdatedAt = now
+	return o.Validate()
+}
+
+// FindItem returns the index of the item with the given product ID, or -1
+func (o Order) FindItem(productID string) int {
+	for i, item := range o.Items {
+		if item.ProductID == productID {
+			return i
+		}
+	}
+	return -1
 }
 
 // OrderStatus represents the current status of an order
@@ -29,14 +215,13 @@ const (
 	OrderStatusProcessing OrderStatus = "PROCESSING"
 	OrderStatusCompleted  OrderStatus = "COMPLETED"
 	OrderStatusCancelled  OrderStatus = "CANCELLED"
-	OrderStatusExpedited  OrderStatus = "EXPEDITED"
 	OrderStatusFailed     OrderStatus = "FAILED"
 )
 
 // ValidationRequest represents the request to validate an order
 type ValidationRequest struct {
-	OrderID string  `json:"order_id"`
-	Amount  float64 `json:"amount"`
+	OrderID string `json:"order_id"`
+	Amount  Money  `json:"amount"`
 }
 
 // ValidationResponse represents the response from validation service
@@ -45,12 +230,57 @@ type ValidationResponse struct {
 	Message string `json:"message"`
 }
 
+// PaymentResult represents the outcome of a successful payment workflow
+type PaymentResult struct {
+	AuthorizationID string `json:"authorization_id"`
+	TransactionID   string `json:"transaction_id"`
+	Amount          Money  `json:"amount"`
+}
+
 // WorkflowState represents the current state of the workflow
 type WorkflowState struct {
-	OrderID         string      `json:"order_id"`
-	Status          OrderStatus `json:"status"`
-	ValidationDone  bool        `json:"validation_done"`
-	ProcessingDone  bool        `json:"processing_done"`
-	PaymentDone     bool        `json:"payment_done"`
-	LastUpdated     time.Time   `json:"last_updated"`
+	OrderID        string      `json:"order_id"`
+	Status         OrderStatus `json:"status"`
+	Expedited      bool        `json:"expedited"`
+	Amount         Money       `json:"amount"`
+	ValidationDone bool        `json:"validation_done"`
+	ProcessingDone bool        `json:"processing_done"`
+	PaymentDone    bool        `json:"payment_done"`
+	TransactionID  string      `json:"transaction_id,omitempty"`
+	Refunded       bool        `json:"refunded"`
+	// FailedCompensations lists compensations that could not be completed
+	FailedCompensations []CompensationFailure `json:"failed_compensations,omitempty"`
+	LastUpdated         time.Time             `json:"last_updated"`
+	// Version increases with every change of the state or the timeline
+	Version int64 `json:"version"`
+}
+
+// OrderProgress is the state and the timeline of an order workflow at one version
+type OrderProgress struct {
+	State    WorkflowState   `json:"state"`
+	Timeline []TimelineEvent `json:"timeline"`
+	// Final is set once the workflow is closing; no further changes follow
+	Final bool `json:"final"`
+}
+
+// CompensationFailure records a compensating activity that failed during a saga rollback
+type CompensationFailure struct {
+	Step  string `json:"step"`
+	Error string `json:"error"`
+}
+
+// TimelineEvent records one step of an order workflow, or a signal or update it received
+type TimelineEvent struct {
+	Step string `json:"step"`
+	// Trigger names the signal or update that caused the event, e.g. "signal:cancel"
+	Trigger   string      `json:"trigger,omitempty"`
+	Status    OrderStatus `json:"status"`
+	StartedAt time.Time   `json:"started_at"`
+	EndedAt   *time.Time  `json:"ended_at,omitempty"`
+	// Attempts is set for an activity that failed after its retry policy ran out; the attempts
+	// of other steps are not known to the workflow
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Count is the number of ignored signals an event stands for; the event shows the latest
+	Count int `json:"count,omitempty"`
 }
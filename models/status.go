@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// statusTransitions lists the statuses each status may move to. Statuses without
+// an entry are terminal.
+var statusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusValidated, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusValidated:  {OrderStatusProcessing, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusProcessing: {OrderStatusCompleted, OrderStatusFailed},
+}
+
+// Transition checks that an order may move from one status to another
+func Transition(from, to OrderStatus) error {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("illegal order status transition from %s to %s", from, to)
+}
+
+// IsTerminal reports whether no further status transition is possible
+func (s OrderStatus) IsTerminal() bool {
+	return len(statusTransitions[s]) == 0
+}
+
+// SetStatus moves the workflow to a new status if the transition is legal
+func (s *WorkflowState) SetStatus(to OrderStatus, at time.Time) error {
+	if err := Transition(s.Status, to); err != nil {
+		return err
+	}
+	s.Status = to
+	s.LastUpdated = at
+	s.Version++
+	return nil
+}
@@ -0,0 +1,715 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"temporal-order-system/api"
+	"temporal-order-system/logging"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/google/uuid"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+)
+
+// startCommand starts order workflows and, unless -no-wait is set, waits for their results
+func startCommand(args []string) error {
+	fs, opts := newFlagSet("start", "Start order workflows. Without -order-file or -items-csv, one order of sample items\nadding up to -amount is started.")
+	orderID := fs.String("order-id", "", "Order ID (optional, auto-generated if not provided)")
+	amount := fs.String("amount", "1000.00", "Order amount as a decimal, e.g. 333.33; must match the item total of a file order")
+	currency := fs.String("currency", models.DefaultCurrency, "ISO-4217 currency code of the amount and of CSV prices")
+	orderFile := fs.String("order-file", "", "JSON file with an order, or an array of orders")
+	itemsCSV := fs.String("items-csv", "", "CSV file of items: product_id,name,quantity,price[,currency][,order_id]")
+	customerName := fs.String("customer-name", "", "Customer name (PII)")
+	customerEmail := fs.String("customer-email", "", "Customer email (PII)")
+	cardToken := fs.String("card-token", "", "Payment card token (PII)")
+	taskQueue := fs.String("task-queue", TaskQueueName, "Task queue of the order workers")
+	concurrency := fs.Int("concurrency", 4, "How many orders of a file are started and awaited at once")
+	noWait := fs.Bool("no-wait", false, "Return once the workflows are started instead of waiting for their results")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+	if *concurrency <= 0 {
+		return usagef("-concurrency must be positive")
+	}
+
+	orderAmount, err := models.ParseMoney(*amount, *currency)
+	if err != nil {
+		return usagef("invalid order amount: %v", err)
+	}
+	customer := models.Order{
+		ID:            *orderID,
+		CustomerName:  *customerName,
+		CustomerEmail: *customerEmail,
+		CardToken:     *cardToken,
+	}
+
+	var orders []models.Order
+	switch {
+	case *orderFile != "" && *itemsCSV != "":
+		return usagef("use either -order-file or -items-csv")
+	case *orderFile != "":
+		orders, err = loadOrderFile(*orderFile)
+	case *itemsCSV != "":
+		orders, err = loadItemsCSV(*itemsCSV, *currency)
+	default:
+		customer.Amount = orderAmount
+		order, err := sampleOrder(customer)
+		if err != nil {
+			return err
+		}
+		orders = []models.Order{order}
+	}
+	if err != nil {
+		return &usageError{message: err.Error()}
+	}
+
+	if *orderFile != "" || *itemsCSV != "" {
+		// An amount or ID on the command line only makes sense for a single order
+		var explicitAmount *models.Money
+		if isFlagSet(fs, "amount") {
+			explicitAmount = &orderAmount
+		}
+		if len(orders) > 1 && (explicitAmount != nil || *orderID != "") {
+			return usagef("-amount and -order-id apply to a single order, the file has %d", len(orders))
+		}
+
+		seen := map[string]bool{}
+		for i := range orders {
+			orders[i] = withCustomer(orders[i], customer)
+			if orders[i], err = prepareOrder(orders[i], explicitAmount); err != nil {
+				return &usageError{message: err.Error()}
+			}
+			if seen[orders[i].ID] {
+				return usagef("duplicate order ID %s", orders[i].ID)
+			}
+			seen[orders[i].ID] = true
+		}
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	if len(orders) == 1 {
+		result, err := startOrder(ctx, c, orders[0], *taskQueue, !*noWait)
+		if result.Status == "" {
+			return err
+		}
+		if printErr := opts.printResult(result); printErr != nil {
+			return printErr
+		}
+		return err
+	}
+
+	slog.Info("Starting orders", "orders", len(orders), "concurrency", *concurrency)
+	results := make([]workflowResult, len(orders))
+	errs := make([]error, len(orders))
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i, order := range orders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i], errs[i] = startOrder(ctx, c, order, *taskQueue, !*noWait)
+			if results[i].Status == "" {
+				results[i] = workflowResult{WorkflowID: workflows.OrderWorkflowID(order.ID), Status: "NotStarted", Error: errs[i].Error()}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := opts.printResults(results); err != nil {
+		return err
+	}
+	return newBulkError(errs)
+}
+
+// startOrder starts the workflow of an order and, when wait is set, waits for its result.
+// The result has no status when the workflow was not started.
+func startOrder(ctx context.Context, c client.Client, order models.Order, taskQueue string, wait bool) (workflowResult, error) {
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        workflows.OrderWorkflowID(order.ID),
+		TaskQueue: taskQueue,
+		// A running order with the same ID is an error, not a handle to the existing run
+		WorkflowExecutionErrorWhenAlreadyStarted: true,
+	}
+
+	slog.Info("Starting workflow", logging.FieldOrderID, order.ID, "amount", order.Amount.String())
+
+	we, err := c.ExecuteWorkflow(ctx, workflowOptions, workflows.OrderWorkflow, order)
+	if err != nil {
+		return workflowResult{}, fmt.Errorf("unable to execute workflow for order %s: %w", order.ID, err)
+	}
+
+	if !wait {
+		slog.Info("Workflow started, follow it with starter watch", logging.FieldWorkflowID, we.GetID())
+		return workflowResult{WorkflowID: we.GetID(), RunID: we.GetRunID(), Status: "Running"}, nil
+	}
+	return getResult(ctx, we)
+}
+
+// withCustomer fills the customer fields of an order that the order leaves empty
+func withCustomer(order, customer models.Order) models.Order {
+	if order.ID == "" {
+		order.ID = customer.ID
+	}
+	if order.CustomerName == "" {
+		order.CustomerName = customer.CustomerName
+	}
+	if order.CustomerEmail == "" {
+		order.CustomerEmail = customer.CustomerEmail
+	}
+	if order.CardToken == "" {
+		order.CardToken = customer.CardToken
+	}
+	return order
+}
+
+// sampleOrder fills an order with two sample items adding up to its amount
+func sampleOrder(order models.Order) (models.Order, error) {
+	// Generate order ID if not provided
+	if order.ID == "" {
+		order.ID = uuid.New().String()
+	}
+	amount := order.Amount
+
+	order.Items = []models.OrderItem{
+		{
+			ProductID: "PROD-001",
+			Name:      "Sample Product 1",
+			Quantity:  2,
+			Price:     models.NewMoneyFromMajor(300, amount.Currency),
+		},
+		{
+			ProductID: "PROD-002",
+			Name:      "Sample Product 2",
+			Quantity:  1,
+			Price:     models.NewMoneyFromMajor(400, amount.Currency),
+		},
+	}
+	order.Status = models.OrderStatusPending
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = time.Now()
+
+	// Adjust items to match the specified amount
+	// Distribute amount evenly across all units; the remainder that cannot be split
+	// evenly goes to a single-quantity item so the item total stays exact
+	totalQuantity := int64(0)
+	for _, item := range order.Items {
+		totalQuantity += int64(item.Quantity)
+	}
+
+	unitPrice := amount.MinorUnits / totalQuantity
+	remainder := amount.MinorUnits - unitPrice*totalQuantity
+	for i := range order.Items {
+		order.Items[i].Price = models.NewMoney(unitPrice, amount.Currency)
+		if remainder != 0 && order.Items[i].Quantity == 1 {
+			order.Items[i].Price.MinorUnits += remainder
+			remainder = 0
+		}
+	}
+
+	if remainder != 0 {
+		total, err := order.Total()
+		if err != nil {
+			return models.Order{}, fmt.Errorf("failed to calculate order total: %w", err)
+		}
+		slog.Warn("Amount cannot be split evenly across items, using the item total", "amount", amount.String(), "total", total.String())
+		order.Amount = total
+	}
+	return order, nil
+}
+
+// signalCommand sends a signal to an order workflow
+func signalCommand(args []string) error {
+	fs, opts := newFlagSet("signal", "Send the cancel or expedite signal to an order workflow.")
+	t := addTargetFlags(fs)
+	name := fs.String("name", "", "Signal name: cancel or expedite")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	switch *name {
+	case workflows.SignalCancel, workflows.SignalExpedite:
+	default:
+		return usagef("unknown signal %q: use cancel or expedite", *name)
+	}
+	return sendSignal(opts, t, *name)
+}
+
+// cancelCommand cancels an order with the cancel signal. The workflow then compensates its
+// completed steps; a Temporal cancellation request would cancel the compensations too.
+func cancelCommand(args []string) error {
+	fs, opts := newFlagSet("cancel", "Cancel an order. Completed steps are compensated and payments refunded.")
+	t := addTargetFlags(fs)
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+	return sendSignal(opts, t, workflows.SignalCancel)
+}
+
+// sendSignal sends a signal to the target workflow
+func sendSignal(opts *options, t *target, signal string) error {
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	if err := c.SignalWorkflow(ctx, workflowID, t.runID, signal, signal); err != nil {
+		return fmt.Errorf("failed to send signal: %w", err)
+	}
+	return opts.printAction(actionResult{WorkflowID: workflowID, Action: "signal", Name: signal})
+}
+
+// updateCommand amends an order with an update and prints the amended order
+func updateCommand(args []string) error {
+	fs, opts := newFlagSet("update", "Amend an order. The update is rejected when the order can no longer change.")
+	t := addTargetFlags(fs)
+	name := fs.String("name", "", "Update name: add-item, remove-item, change-quantity, change-shipping-address or apply-discount")
+	arg := fs.String("arg", "", "JSON argument of the update, e.g. '\"SAVE10\"' for apply-discount")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	var updateArg interface{}
+	switch *name {
+	case workflows.UpdateAddItem:
+		updateArg = &models.OrderItem{}
+	case workflows.UpdateRemoveItem, workflows.UpdateApplyDiscount:
+		updateArg = new(string)
+	case workflows.UpdateChangeQuantity:
+		updateArg = &workflows.QuantityChange{}
+	case workflows.UpdateChangeShippingAddress:
+		updateArg = &models.Address{}
+	default:
+		return usagef("unknown update %q: use add-item, remove-item, change-quantity, change-shipping-address or apply-discount", *name)
+	}
+	if err := json.Unmarshal([]byte(*arg), updateArg); err != nil {
+		return usagef("invalid update argument: %v", err)
+	}
+
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	handle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   workflowID,
+		RunID:        t.runID,
+		UpdateName:   *name,
+		Args:         []interface{}{updateArg},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err != nil {
+		return fmt.Errorf("update rejected: %w", err)
+	}
+
+	var order models.Order
+	if err := handle.Get(ctx, &order); err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	slog.Info("Update applied", "update", *name, "amount", order.Amount.String())
+	return opts.printOrder(order)
+}
+
+// queryCommand prints the state or the timeline of an order
+func queryCommand(args []string) error {
+	fs, opts := newFlagSet("query", "Query the state or the step timeline of an order workflow.")
+	t := addTargetFlags(fs)
+	queryType := fs.String("type", workflows.QueryState, "Query: state or timeline")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+	return runQuery(opts, t, *queryType)
+}
+
+// timelineCommand prints the step timeline of an order workflow
+func timelineCommand(args []string) error {
+	fs, opts := newFlagSet("timeline", "Query the step timeline of an order workflow.")
+	t := addTargetFlags(fs)
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+	return runQuery(opts, t, workflows.QueryTimeline)
+}
+
+// runQuery queries the target workflow and prints the result
+func runQuery(opts *options, t *target, queryType string) error {
+	if queryType != workflows.QueryState && queryType != workflows.QueryTimeline {
+		return usagef("unknown query %q: use state or timeline", queryType)
+	}
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	resp, err := c.QueryWorkflow(ctx, workflowID, t.runID, queryType)
+	if err != nil {
+		return fmt.Errorf("failed to query workflow: %w", err)
+	}
+
+	if queryType == workflows.QueryTimeline {
+		var events []models.TimelineEvent
+		if err := resp.Get(&events); err != nil {
+			return fmt.Errorf("failed to decode query result: %w", err)
+		}
+		return opts.printTimeline(events)
+	}
+
+	var state models.WorkflowState
+	if err := resp.Get(&state); err != nil {
+		return fmt.Errorf("failed to decode query result: %w", err)
+	}
+	return opts.printState(state)
+}
+
+// describeCommand prints the execution details of an order workflow
+func describeCommand(args []string) error {
+	fs, opts := newFlagSet("describe", "Describe the execution of an order workflow and its pending activities.")
+	t := addTargetFlags(fs)
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	resp, err := c.DescribeWorkflowExecution(ctx, workflowID, t.runID)
+	if err != nil {
+		return fmt.Errorf("failed to describe workflow: %w", err)
+	}
+
+	info := resp.GetWorkflowExecutionInfo()
+	description := workflowDescription{
+		workflowSummary: summarize(info),
+		TaskQueue:       info.GetTaskQueue(),
+		HistoryLength:   info.GetHistoryLength(),
+	}
+	for _, activity := range resp.GetPendingActivities() {
+		description.PendingActivities = append(description.PendingActivities, pendingActivity{
+			Type:        activity.GetActivityType().GetName(),
+			State:       activity.GetState().String(),
+			Attempt:     activity.GetAttempt(),
+			LastFailure: activity.GetLastFailure().GetMessage(),
+		})
+	}
+	return opts.printDescription(description)
+}
+
+// listCommand lists order workflows matching a visibility query
+func listCommand(args []string) error {
+	fs, opts := newFlagSet("list", "List order workflows, newest first.")
+	query := fs.String("query", "WorkflowType = 'OrderWorkflow'", "Visibility query, e.g. \"ExecutionStatus = 'Running'\"")
+	limit := fs.Int("limit", 20, "Maximum number of workflows")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return usagef("-limit must be positive")
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	summaries := []workflowSummary{}
+	var nextPageToken []byte
+	for len(summaries) < *limit {
+		resp, err := c.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			PageSize:      int32(*limit - len(summaries)),
+			NextPageToken: nextPageToken,
+			Query:         *query,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list workflows: %w", err)
+		}
+		for _, info := range resp.GetExecutions() {
+			if len(summaries) < *limit {
+				summaries = append(summaries, summarize(info))
+			}
+		}
+		nextPageToken = resp.GetNextPageToken()
+		if len(nextPageToken) == 0 {
+			break
+		}
+	}
+	return opts.printSummaries(summaries)
+}
+
+// terminateCommand terminates an order workflow
+func terminateCommand(args []string) error {
+	fs, opts := newFlagSet("terminate", "Terminate an order workflow. Nothing is compensated; prefer cancel.")
+	t := addTargetFlags(fs)
+	reason := fs.String("reason", "terminated by starter", "Reason recorded in the history")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	if err := c.TerminateWorkflow(ctx, workflowID, t.runID, *reason); err != nil {
+		return fmt.Errorf("failed to terminate workflow: %w", err)
+	}
+	return opts.printAction(actionResult{WorkflowID: workflowID, Action: "terminate", Name: *reason})
+}
+
+// resultCommand waits for an order workflow to close and prints how it closed
+func resultCommand(args []string) error {
+	fs, opts := newFlagSet("result", "Wait for an order workflow to close. The exit code tells how it closed.")
+	t := addTargetFlags(fs)
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	return opts.waitForResult(ctx, c.GetWorkflow(ctx, workflowID, t.runID))
+}
+
+// waitForResult waits for a workflow run and prints its result. The workflow's error is returned
+// so it sets the exit code.
+func (o *options) waitForResult(ctx context.Context, run client.WorkflowRun) error {
+	result, err := getResult(ctx, run)
+	if result.Status == "" {
+		return err
+	}
+	if printErr := o.printResult(result); printErr != nil {
+		return printErr
+	}
+	return err
+}
+
+// getResult waits for a workflow run. The error is the workflow's when it closed unsuccessfully;
+// the result has no status when there is no outcome, e.g. the workflow does not exist.
+func getResult(ctx context.Context, run client.WorkflowRun) (workflowResult, error) {
+	err := run.Get(ctx, nil)
+
+	var workflowErr *temporal.WorkflowExecutionError
+	if err != nil && !errors.As(err, &workflowErr) {
+		// Not a workflow outcome, e.g. the workflow does not exist or -timeout elapsed
+		return workflowResult{}, fmt.Errorf("failed to get workflow result: %w", err)
+	}
+
+	result := workflowResult{WorkflowID: run.GetID(), RunID: run.GetRunID(), Status: workflowStatus(err)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, err
+}
+
+// watchCommand prints every change of the order state until the workflow closes
+func watchCommand(args []string) error {
+	fs, opts := newFlagSet("watch", "Follow an order: print every state change until the workflow closes, then its result.\nChanges are pushed by the wait-for-change update; older workflows are polled.")
+	t := addTargetFlags(fs)
+	interval := fs.Duration("interval", time.Second, "How often to poll the state of workflows without the wait-for-change update")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	workflowID, err := t.resolve()
+	if err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	printer := opts.newProgressPrinter()
+	err = api.WatchOrder(ctx, c, workflowID, t.runID, -1, printer)
+	var notFound *serviceerror.NotFound
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.As(err, &notFound):
+		return err
+	default:
+		// Workflows started before the update existed reject it
+		slog.Warn("Waiting for changes failed, polling the state query instead", "error", err, "interval", interval.String())
+		if err := pollProgress(ctx, c, workflowID, t.runID, *interval, printer); err != nil {
+			return err
+		}
+	}
+	return opts.waitForResult(ctx, c.GetWorkflow(ctx, workflowID, t.runID))
+}
+
+// pollProgress queries the state of a workflow every interval and prints it when it changes,
+// until the workflow closes
+func pollProgress(ctx context.Context, c client.Client, workflowID, runID string, interval time.Duration, printer func(models.OrderProgress) error) error {
+	var last *models.WorkflowState
+	for {
+		resp, err := c.DescribeWorkflowExecution(ctx, workflowID, runID)
+		if err != nil {
+			return fmt.Errorf("failed to describe workflow: %w", err)
+		}
+		info := resp.GetWorkflowExecutionInfo()
+
+		// Queries need a worker; keep watching while none answers
+		if queryResp, err := c.QueryWorkflow(ctx, workflowID, info.GetExecution().GetRunId(), workflows.QueryState); err != nil {
+			slog.Warn("Failed to query workflow state", "error", err)
+		} else {
+			var state models.WorkflowState
+			if err := queryResp.Get(&state); err != nil {
+				return fmt.Errorf("failed to decode query result: %w", err)
+			}
+			if last == nil || !state.LastUpdated.Equal(last.LastUpdated) || state.Status != last.Status {
+				if err := printer(models.OrderProgress{State: state}); err != nil {
+					return err
+				}
+				last = &state
+			}
+		}
+
+		if info.GetStatus() != enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// apiCommand serves the REST API of the api package until interrupted
+func apiCommand(args []string) error {
+	fs, opts := newFlagSet("api", "Serve the REST API for submitting and managing orders. The OpenAPI spec is at /openapi.yaml.")
+	listen := fs.String("listen", envOr("API_ADDRESS", ":8090"), "Listen address")
+	taskQueue := fs.String("task-queue", TaskQueueName, "Task queue of the order workers")
+	requestTimeout := fs.Duration("request-timeout", api.DefaultRequestTimeout, "Bound on the Temporal calls of each request")
+	maxWatchers := fs.Int("max-watchers", api.DefaultMaxWatchersPerOrder, "Event streams allowed per order at a time")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	c, err := opts.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := opts.context()
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           api.NewServer(c, api.Options{TaskQueue: *taskQueue, RequestTimeout: *requestTimeout, MaxWatchersPerOrder: *maxWatchers}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("Serving the order API", "listen", *listen, "namespace", opts.namespace, logging.FieldTaskQueue, *taskQueue)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("order API failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down the order API")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), *requestTimeout)
+	defer shutdownCancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"temporal-order-system/models"
+)
+
+// csvColumns are the columns of an items CSV file; order_id and currency are optional
+var csvColumns = []string{"order_id", "product_id", "name", "quantity", "price", "currency"}
+
+// loadOrderFile reads one order, or an array of orders, from a JSON file. Fields that are not
+// part of models.Order are rejected, so typos do not pass silently.
+func loadOrderFile(path string) ([]models.Order, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var orders []models.Order
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = decoder.Decode(&orders)
+	} else {
+		var order models.Order
+		err = decoder.Decode(&order)
+		orders = append(orders, order)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid order file %s: %w", path, err)
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("order file %s has no orders", path)
+	}
+	return orders, nil
+}
+
+// loadItemsCSV reads order items from a CSV file with a header row. With an order_id column,
+// rows are grouped into one order per ID in the order they first appear; without it, all rows
+// are the items of a single order. Prices are decimals in the currency column, or currency.
+func loadItemsCSV(path, currency string) ([]models.Order, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid items CSV %s: missing header: %w", path, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("invalid items CSV %s: unknown column %q, expected %s", path, name, strings.Join(csvColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"product_id", "name", "quantity", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("invalid items CSV %s: missing column %q", path, required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var orders []models.Order
+	index := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid items CSV %s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		quantity, err := strconv.Atoi(field(record, "quantity"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid items CSV %s line %d: quantity: %w", path, line, err)
+		}
+		itemCurrency := currency
+		if c := field(record, "currency"); c != "" {
+			itemCurrency = c
+		}
+		price, err := models.ParseMoney(field(record, "price"), itemCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid items CSV %s line %d: price: %w", path, line, err)
+		}
+
+		orderID := field(record, "order_id")
+		if _, ok := columns["order_id"]; ok && orderID == "" {
+			return nil, fmt.Errorf("invalid items CSV %s line %d: order_id is required", path, line)
+		}
+		i, ok := index[orderID]
+		if !ok {
+			i = len(orders)
+			index[orderID] = i
+			orders = append(orders, models.Order{ID: orderID})
+		}
+		orders[i].Items = append(orders[i].Items, models.OrderItem{
+			ProductID: field(record, "product_id"),
+			Name:      field(record, "name"),
+			Quantity:  quantity,
+			Price:     price,
+		})
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("items CSV %s has no items", path)
+	}
+	return orders, nil
+}
+
+// prepareOrder readies a loaded order for submission. The amount is computed from the items
+// and discount code; an amount given in the file or as explicitAmount must match it.
+func prepareOrder(order models.Order, explicitAmount *models.Money) (models.Order, error) {
+	if explicitAmount != nil {
+		order.Amount = *explicitAmount
+	}
+	if err := order.Prepare(time.Now()); err != nil {
+		return models.Order{}, err
+	}
+	return order, nil
+}
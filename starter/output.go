@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"temporal-order-system/models"
+
+	workflowpb "go.temporal.io/api/workflow/v1"
+)
+
+// workflowResult is how a workflow closed, or that it is running
+type workflowResult struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// actionResult acknowledges a signal or termination
+type actionResult struct {
+	WorkflowID string `json:"workflow_id"`
+	Action     string `json:"action"`
+	Name       string `json:"name"`
+}
+
+// workflowSummary is a workflow in the list
+type workflowSummary struct {
+	WorkflowID string     `json:"workflow_id"`
+	RunID      string     `json:"run_id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	StartTime  time.Time  `json:"start_time"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+}
+
+// workflowDescription is the output of describe
+type workflowDescription struct {
+	workflowSummary
+	TaskQueue         string            `json:"task_queue"`
+	HistoryLength     int64             `json:"history_length"`
+	PendingActivities []pendingActivity `json:"pending_activities,omitempty"`
+}
+
+// pendingActivity is an activity the workflow is waiting for
+type pendingActivity struct {
+	Type        string `json:"type"`
+	State       string `json:"state"`
+	Attempt     int32  `json:"attempt"`
+	LastFailure string `json:"last_failure,omitempty"`
+}
+
+// summarize converts the execution info returned by Temporal
+func summarize(info *workflowpb.WorkflowExecutionInfo) workflowSummary {
+	summary := workflowSummary{
+		WorkflowID: info.GetExecution().GetWorkflowId(),
+		RunID:      info.GetExecution().GetRunId(),
+		Type:       info.GetType().GetName(),
+		Status:     info.GetStatus().String(),
+		StartTime:  info.GetStartTime().AsTime(),
+	}
+	if info.GetCloseTime() != nil {
+		closeTime := info.GetCloseTime().AsTime()
+		summary.CloseTime = &closeTime
+	}
+	return summary
+}
+
+// print writes v as indented JSON, or as the table written by table
+func (o *options) print(v interface{}, table func(w io.Writer)) error {
+	if o.output == "json" {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal output: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// printResult prints a workflow result
+func (o *options) printResult(result workflowResult) error {
+	return o.print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "WORKFLOW ID\tRUN ID\tSTATUS\tERROR")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.WorkflowID, result.RunID, result.Status, orDash(result.Error))
+	})
+}
+
+// printResults prints the results of a bulk start
+func (o *options) printResults(results []workflowResult) error {
+	return o.print(results, func(w io.Writer) {
+		fmt.Fprintln(w, "WORKFLOW ID\tRUN ID\tSTATUS\tERROR")
+		for _, result := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.WorkflowID, orDash(result.RunID), result.Status, orDash(result.Error))
+		}
+	})
+}
+
+// printAction prints the acknowledgement of a signal or termination
+func (o *options) printAction(result actionResult) error {
+	return o.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%s %s sent to %s\n", result.Action, result.Name, result.WorkflowID)
+	})
+}
+
+// printOrder prints an order
+func (o *options) printOrder(order models.Order) error {
+	return o.print(order, func(w io.Writer) {
+		fmt.Fprintf(w, "ORDER\t%s\n", order.ID)
+		fmt.Fprintf(w, "STATUS\t%s\n", order.Status)
+		fmt.Fprintf(w, "AMOUNT\t%s\n", order.Amount)
+		fmt.Fprintf(w, "DISCOUNT\t%s\t%s\n", order.Discount, orDash(order.DiscountCode))
+		fmt.Fprintln(w, "\nPRODUCT\tNAME\tQUANTITY\tPRICE")
+		for _, item := range order.Items {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", item.ProductID, item.Name, item.Quantity, item.Price)
+		}
+	})
+}
+
+// printState prints the state of an order workflow
+func (o *options) printState(state models.WorkflowState) error {
+	return o.print(state, func(w io.Writer) {
+		fmt.Fprintf(w, "ORDER\t%s\n", state.OrderID)
+		fmt.Fprintf(w, "STATUS\t%s\n", state.Status)
+		fmt.Fprintf(w, "AMOUNT\t%s\n", state.Amount)
+		fmt.Fprintf(w, "EXPEDITED\t%t\n", state.Expedited)
+		fmt.Fprintf(w, "VALIDATED\t%t\n", state.ValidationDone)
+		fmt.Fprintf(w, "PROCESSED\t%t\n", state.ProcessingDone)
+		fmt.Fprintf(w, "PAID\t%t\n", state.PaymentDone)
+		fmt.Fprintf(w, "TRANSACTION\t%s\n", orDash(state.TransactionID))
+		fmt.Fprintf(w, "REFUNDED\t%t\n", state.Refunded)
+		for _, failure := range state.FailedCompensations {
+			fmt.Fprintf(w, "FAILED COMPENSATION\t%s: %s\n", failure.Step, failure.Error)
+		}
+		fmt.Fprintf(w, "LAST UPDATED\t%s\n", state.LastUpdated.Format(time.RFC3339))
+	})
+}
+
+// printTimeline prints the step timeline of an order workflow
+func (o *options) printTimeline(events []models.TimelineEvent) error {
+	return o.print(events, func(w io.Writer) {
+		fmt.Fprintln(w, "STEP\tTRIGGER\tSTATUS\tSTARTED\tDURATION\tATTEMPTS\tERROR")
+		for _, e := range events {
+			duration := "running"
+			if e.EndedAt != nil {
+				duration = e.EndedAt.Sub(e.StartedAt).String()
+			}
+			attempts := ""
+			if e.Attempts > 0 {
+				attempts = strconv.Itoa(e.Attempts)
+			}
+			message := e.Error
+			if e.Count > 1 {
+				message = fmt.Sprintf("%s (%d times)", e.Error, e.Count)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				e.Step, orDash(e.Trigger), e.Status, e.StartedAt.Format(time.RFC3339), duration, orDash(attempts), message)
+		}
+	})
+}
+
+// printDescription prints the execution details of a workflow
+func (o *options) printDescription(d workflowDescription) error {
+	return o.print(d, func(w io.Writer) {
+		fmt.Fprintf(w, "WORKFLOW ID\t%s\n", d.WorkflowID)
+		fmt.Fprintf(w, "RUN ID\t%s\n", d.RunID)
+		fmt.Fprintf(w, "TYPE\t%s\n", d.Type)
+		fmt.Fprintf(w, "STATUS\t%s\n", d.Status)
+		fmt.Fprintf(w, "TASK QUEUE\t%s\n", d.TaskQueue)
+		fmt.Fprintf(w, "STARTED\t%s\n", d.StartTime.Format(time.RFC3339))
+		fmt.Fprintf(w, "CLOSED\t%s\n", formatTime(d.CloseTime))
+		fmt.Fprintf(w, "HISTORY LENGTH\t%d\n", d.HistoryLength)
+		if len(d.PendingActivities) > 0 {
+			fmt.Fprintln(w, "\nACTIVITY\tSTATE\tATTEMPT\tLAST FAILURE")
+			for _, a := range d.PendingActivities {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", a.Type, a.State, a.Attempt, orDash(a.LastFailure))
+			}
+		}
+	})
+}
+
+// printSummaries prints a list of workflows
+func (o *options) printSummaries(summaries []workflowSummary) error {
+	return o.print(summaries, func(w io.Writer) {
+		fmt.Fprintln(w, "WORKFLOW ID\tRUN ID\tSTATUS\tSTARTED\tCLOSED")
+		for _, s := range summaries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.WorkflowID, s.RunID, s.Status, s.StartTime.Format(time.RFC3339), formatTime(s.CloseTime))
+		}
+	})
+}
+
+// newProgressPrinter returns a function printing each state of a watched workflow on its own
+// line: the state as one JSON object per line, or a row of fixed-width columns that also
+// shows the latest step of the timeline
+func (o *options) newProgressPrinter() func(progress models.OrderProgress) error {
+	if o.output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		return func(progress models.OrderProgress) error {
+			return encoder.Encode(progress.State)
+		}
+	}
+
+	header := false
+	return func(progress models.OrderProgress) error {
+		if !header {
+			fmt.Printf("%-20s  %-10s  %-9s  %-9s  %-9s  %-4s  %-8s  %s\n", "UPDATED", "STATUS", "EXPEDITED", "VALIDATED", "PROCESSED", "PAID", "REFUNDED", "STEP")
+			header = true
+		}
+		state := progress.State
+		_, err := fmt.Printf("%-20s  %-10s  %-9t  %-9t  %-9t  %-4t  %-8t  %s\n", state.LastUpdated.Format(time.RFC3339), state.Status,
+			state.Expedited, state.ValidationDone, state.ProcessingDone, state.PaymentDone, state.Refunded, latestStep(progress.Timeline))
+		return err
+	}
+}
+
+// latestStep describes the last event of a timeline, e.g. "ProcessOrder (running)"
+func latestStep(events []models.TimelineEvent) string {
+	if len(events) == 0 {
+		return "-"
+	}
+	e := events[len(events)-1]
+	switch {
+	case e.EndedAt == nil:
+		return e.Step + " (running)"
+	case e.Error != "":
+		return e.Step + " (failed)"
+	default:
+		return e.Step + " (done)"
+	}
+}
+
+// formatTime formats an optional time
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// orDash returns s, or "-" for an empty column
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -2,221 +2,384 @@ package main
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"temporal-order-system/codec"
+	"strings"
 	"time"
 
-	"temporal-order-system/models"
+	"temporal-order-system/codec"
+	"temporal-order-system/logging"
+	"temporal-order-system/tracing"
 	"temporal-order-system/workflows"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	sdklog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
 )
 
 const (
 	TaskQueueName = "order-processing-queue"
 )
 
+// Exit codes of the starter commands, so scripts can tell outcomes apart
+const (
+	exitOK               = 0
+	exitError            = 1 // the command failed, e.g. Temporal is unreachable
+	exitUsage            = 2 // invalid command or flags
+	exitNotFound         = 3 // the workflow does not exist
+	exitAlreadyStarted   = 4 // a workflow with the order's ID is already running
+	exitWorkflowFailed   = 5 // the workflow completed with an error
+	exitWorkflowCanceled = 6 // the workflow was cancelled or terminated
+	exitTimeout          = 7 // the workflow or the -timeout of the command timed out
+)
+
+// command is a starter subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands are listed in the usage in this order
+var commands = []command{
+	{name: "start", summary: "Start an order workflow (default command)", run: startCommand},
+	{name: "signal", summary: "Send the cancel or expedite signal", run: signalCommand},
+	{name: "update", summary: "Amend an order with an update", run: updateCommand},
+	{name: "query", summary: "Query the order state or timeline", run: queryCommand},
+	{name: "timeline", summary: "Query the order timeline (query -type timeline)", run: timelineCommand},
+	{name: "describe", summary: "Describe the workflow execution", run: describeCommand},
+	{name: "list", summary: "List order workflows", run: listCommand},
+	{name: "cancel", summary: "Cancel an order, compensating completed steps", run: cancelCommand},
+	{name: "terminate", summary: "Terminate the workflow without compensation", run: terminateCommand},
+	{name: "result", summary: "Wait for the workflow result", run: resultCommand},
+	{name: "watch", summary: "Follow the order state until the workflow closes", run: watchCommand},
+	{name: "api", summary: "Serve the REST API for orders", run: apiCommand},
+}
+
 func main() {
-	// Command line flags
-	orderID := flag.String("order-id", "", "Order ID (optional, auto-generated if not provided)")
-	amount := flag.Float64("amount", 1000.0, "Order amount")
-	signal := flag.String("signal", "", "Send signal to workflow (cancel or expedite)")
-	query := flag.Bool("query", false, "Query workflow state")
-	workflowID := flag.String("workflow-id", "", "Workflow ID for signal/query operations")
-	flag.Parse()
-
-	// Get Temporal server address from environment or use default
-	temporalAddress := os.Getenv("TEMPORAL_ADDRESS")
-	if temporalAddress == "" {
-		temporalAddress = "localhost:7233"
-	}
-
-	// Get or generate encryption key
-	encryptionKey := os.Getenv("ENCRYPTION_KEY")
-	var keyBytes []byte
-	var err error
-
-	if encryptionKey != "" {
-		keyBytes, err = hex.DecodeString(encryptionKey)
-		if err != nil {
-			log.Fatalf("Failed to decode encryption key: %v", err)
-		}
-	} else {
-		// Generate a random 32-byte key for AES-256
-		keyBytes = make([]byte, 32)
-		if _, err := rand.Read(keyBytes); err != nil {
-			log.Fatalf("Failed to generate encryption key: %v", err)
-		}
-		log.Printf("Warning: Using generated encryption key. Set ENCRYPTION_KEY env var to match worker.")
-		log.Printf("Generated key: %s", hex.EncodeToString(keyBytes))
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes the command named by the first argument and returns the exit code
+func run(args []string) int {
+	// Flags without a command start an order, as before subcommands existed
+	name := "start"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	// Create data converter with encryption
-	dataConverter, err := codec.NewEncryptionDataConverter(keyBytes)
-	if err != nil {
-		log.Fatalf("Failed to create encryption data converter: %v", err)
+	if name == "help" {
+		printUsage()
+		return exitOK
 	}
 
-	// Create Temporal client with encryption
-	c, err := client.Dial(client.Options{
-		HostPort:      temporalAddress,
-		DataConverter: dataConverter,
-	})
+	// Messages go to stderr through slog, as text at info level unless LOG_LEVEL and
+	// LOG_FORMAT say otherwise, so stdout stays parseable
+	logOptions, err := logging.OptionsFromEnv(logging.Options{Level: slog.LevelInfo, Format: logging.FormatText})
 	if err != nil {
-		log.Fatalf("Unable to create Temporal client: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
 	}
-	defer c.Close()
+	slog.SetDefault(logging.NewSlogLogger(os.Stderr, logOptions))
 
-	ctx := context.Background()
+	// Export traces as configured by OTEL_TRACES_EXPORTER; spans are flushed before exiting
+	shutdownTracing, err := tracing.Setup(context.Background(), "order-starter")
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		return exitError
+	}
+	defer shutdownTracing(context.Background())
 
-	// Handle signal operations
-	if *signal != "" {
-		if *workflowID == "" {
-			log.Fatal("Workflow ID is required for signal operations. Use -workflow-id flag")
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
-		sendSignal(ctx, c, *workflowID, *signal)
-		return
+		if err != nil {
+			slog.Error("Command failed", "command", name, "error", err)
+		}
+		return exitCode(err)
+	}
+
+	slog.Error("Unknown command", "command", name)
+	printUsage()
+	return exitUsage
+}
+
+// printUsage lists the commands
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: starter <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr, "\nRun 'starter <command> -h' for the flags of a command.")
+}
+
+// usageError is an error in the command line
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// usagef returns a usageError
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// bulkError reports the orders of a bulk start that did not complete
+type bulkError struct {
+	failed int
+	total  int
+	code   int
+}
 
-	// Handle query operations
-	if *query {
-		if *workflowID == "" {
-			log.Fatal("Workflow ID is required for query operations. Use -workflow-id flag")
+// newBulkError returns the error of a bulk start, or nil when every order completed. Its exit
+// code is the highest exit code of the failed orders.
+func newBulkError(errs []error) error {
+	bulk := &bulkError{total: len(errs)}
+	for _, err := range errs {
+		if err != nil {
+			bulk.failed++
+			bulk.code = max(bulk.code, exitCode(err))
 		}
-		queryWorkflowState(ctx, c, *workflowID)
-		return
-	}
-
-	// Start a new workflow
-	startWorkflow(ctx, c, *orderID, *amount)
-}
-
-func startWorkflow(ctx context.Context, c client.Client, orderID string, amount float64) {
-	// Generate order ID if not provided
-	if orderID == "" {
-		orderID = uuid.New().String()
-	}
-
-	// Create order
-	order := models.Order{
-		ID:     orderID,
-		Amount: amount,
-		Items: []models.OrderItem{
-			{
-				ProductID: "PROD-001",
-				Name:      "Sample Product 1",
-				Quantity:  2,
-				Price:     300.0,
-			},
-			{
-				ProductID: "PROD-002",
-				Name:      "Sample Product 2",
-				Quantity:  1,
-				Price:     400.0,
-			},
-		},
-		Status:    models.OrderStatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	// Adjust items to match the specified amount
-	// Distribute amount proportionally across all items based on quantity
-	if len(order.Items) > 0 {
-		totalQuantity := 0
-		for _, item := range order.Items {
-			totalQuantity += item.Quantity
+	}
+	if bulk.failed == 0 {
+		return nil
+	}
+	return bulk
+}
+
+func (e *bulkError) Error() string {
+	return fmt.Sprintf("%d of %d orders did not complete", e.failed, e.total)
+}
+
+// isFlagSet reports whether a flag was given on the command line
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
+	})
+	return set
+}
 
-		if totalQuantity > 0 {
-			for i := range order.Items {
-				itemProportion := float64(order.Items[i].Quantity) / float64(totalQuantity)
-				itemAmount := amount * itemProportion
-				order.Items[i].Price = itemAmount / float64(order.Items[i].Quantity)
-			}
+// exitCode maps the error of a command to its exit code
+func exitCode(err error) int {
+	var bulk *bulkError
+	var usage *usageError
+	var notFound *serviceerror.NotFound
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	var workflowErr *temporal.WorkflowExecutionError
+	var deadline *serviceerror.DeadlineExceeded
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &bulk):
+		return bulk.code
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &notFound):
+		return exitNotFound
+	case errors.As(err, &alreadyStarted):
+		return exitAlreadyStarted
+	case errors.As(err, &workflowErr):
+		switch workflowStatus(err) {
+		case "Canceled", "Terminated":
+			return exitWorkflowCanceled
+		case "TimedOut":
+			return exitTimeout
 		}
+		return exitWorkflowFailed
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &deadline):
+		return exitTimeout
+	default:
+		return exitError
 	}
+}
 
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        fmt.Sprintf("order-workflow-%s", order.ID),
-		TaskQueue: TaskQueueName,
+// workflowStatus names how a workflow closed from the error of its result
+func workflowStatus(err error) string {
+	var workflowErr *temporal.WorkflowExecutionError
+	if err == nil {
+		return "Completed"
+	}
+	if !errors.As(err, &workflowErr) {
+		return "Unknown"
+	}
+
+	// Only the direct cause tells how the workflow closed; an activity timeout fails it
+	switch errors.Unwrap(workflowErr).(type) {
+	case *temporal.CanceledError:
+		return "Canceled"
+	case *temporal.TerminatedError:
+		return "Terminated"
+	case *temporal.TimeoutError:
+		return "TimedOut"
+	default:
+		return "Failed"
 	}
+}
 
-	log.Printf("Starting workflow for order: %s", order.ID)
-	log.Printf("Order amount: $%.2f", order.Amount)
-	log.Printf("Workflow ID: %s", workflowOptions.ID)
+// options are the connection, codec and output flags shared by every command
+type options struct {
+	address   string
+	namespace string
+	output    string
+	timeout   time.Duration
+
+	keyringFile    string
+	kmsEndpoint    string
+	encryptionMode string
+	claimCheckDir  string
+}
 
-	we, err := c.ExecuteWorkflow(ctx, workflowOptions, workflows.OrderWorkflow, order)
-	if err != nil {
-		log.Fatalf("Unable to execute workflow: %v", err)
+// newFlagSet creates the flag set of a command with the shared flags
+func newFlagSet(name, usage string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: starter %s [flags]\n\n%s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
 	}
 
-	log.Printf("Started workflow successfully")
-	log.Printf("WorkflowID: %s", we.GetID())
-	log.Printf("RunID: %s", we.GetRunID())
-	log.Println("\nTo query workflow state, run:")
-	log.Printf("  go run starter/starter.go -query -workflow-id %s\n", we.GetID())
-	log.Println("To send signals, run:")
-	log.Printf("  go run starter/starter.go -signal expedite -workflow-id %s", we.GetID())
-	log.Printf("  go run starter/starter.go -signal cancel -workflow-id %s", we.GetID())
+	opts := &options{}
+	fs.StringVar(&opts.address, "address", envOr("TEMPORAL_ADDRESS", "localhost:7233"), "Temporal server address")
+	fs.StringVar(&opts.namespace, "namespace", envOr("TEMPORAL_NAMESPACE", "default"), "Temporal namespace")
+	fs.StringVar(&opts.output, "output", "table", "Output format: table or json")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "Give up after this duration, e.g. 30s (default no limit)")
+	fs.StringVar(&opts.keyringFile, "keyring-file", "", "JSON keyring file (overrides ENCRYPTION_KEYRING_FILE)")
+	fs.StringVar(&opts.kmsEndpoint, "kms-endpoint", "", "KMS issuing data keys (overrides ENCRYPTION_KMS_ENDPOINT)")
+	fs.StringVar(&opts.encryptionMode, "encryption-mode", "", "payload or fields (overrides ENCRYPTION_MODE)")
+	fs.StringVar(&opts.claimCheckDir, "claim-check-dir", "", "Directory of offloaded payloads (overrides CLAIM_CHECK_DIR)")
+	return fs, opts
+}
 
-	// Wait for workflow completion (optional)
-	log.Println("\nWaiting for workflow to complete...")
-	err = we.Get(ctx, nil)
-	if err != nil {
-		log.Printf("Workflow completed with error: %v", err)
-	} else {
-		log.Println("Workflow completed successfully!")
+// parse parses the command line and validates the shared flags
+func (o *options) parse(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{message: err.Error()}
+	}
+	if o.output != "table" && o.output != "json" {
+		return usagef("invalid -output %q: use table or json", o.output)
 	}
+	return nil
 }
 
-func sendSignal(ctx context.Context, c client.Client, workflowID, signal string) {
-	log.Printf("Sending signal '%s' to workflow: %s", signal, workflowID)
+// context returns the context of the command, bounded by -timeout
+func (o *options) context() (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(context.Background(), o.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
 
-	var signalName string
-	switch signal {
-	case "cancel":
-		signalName = workflows.SignalCancel
-	case "expedite":
-		signalName = workflows.SignalExpedite
-	default:
-		log.Fatalf("Unknown signal: %s. Valid signals: cancel, expedite", signal)
+// dial connects to Temporal with the encrypting data converter
+func (o *options) dial() (client.Client, error) {
+	// The codec flags take precedence over their environment variables
+	for name, value := range map[string]string{
+		"ENCRYPTION_KEYRING_FILE": o.keyringFile,
+		"ENCRYPTION_KMS_ENDPOINT": o.kmsEndpoint,
+		"ENCRYPTION_MODE":         o.encryptionMode,
+		"CLAIM_CHECK_DIR":         o.claimCheckDir,
+	} {
+		if value != "" {
+			os.Setenv(name, value)
+		}
 	}
 
-	err := c.SignalWorkflow(ctx, workflowID, "", signalName, signal)
+	// Load the encryption setup; startup fails without keys unless ENCRYPTION_DEV_MODE is set
+	encryption, err := codec.LoadEncryptionConfig()
 	if err != nil {
-		log.Fatalf("Failed to send signal: %v", err)
+		return nil, fmt.Errorf("failed to load encryption config: %w", err)
+	}
+	if encryption.GeneratedKey != nil {
+		slog.Warn("Dev mode, using generated encryption key. Set ENCRYPTION_KEY to the worker's key.",
+			"generated_key", hex.EncodeToString(encryption.GeneratedKey))
 	}
 
-	log.Printf("Signal '%s' sent successfully", signal)
-}
-
-func queryWorkflowState(ctx context.Context, c client.Client, workflowID string) {
-	log.Printf("Querying workflow state: %s", workflowID)
+	// Create data converter with compression and encryption
+	dataConverter, err := encryption.Builder().Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption data converter: %w", err)
+	}
 
-	resp, err := c.QueryWorkflow(ctx, workflowID, "", workflows.QueryState)
+	// SDK logs follow LOG_LEVEL and LOG_FORMAT, warnings as text by default; they go to stderr
+	// so stdout stays parseable
+	logOptions, err := logging.OptionsFromEnv(logging.Options{Level: slog.LevelWarn, Format: logging.FormatText})
 	if err != nil {
-		log.Fatalf("Failed to query workflow: %v", err)
+		return nil, err
 	}
 
-	var state models.WorkflowState
-	if err := resp.Get(&state); err != nil {
-		log.Fatalf("Failed to decode query result: %v", err)
+	tracingInterceptor, err := tracing.NewInterceptor(otel.GetTracerProvider())
+	if err != nil {
+		return nil, err
 	}
 
-	// Pretty print the state
-	stateJSON, err := json.MarshalIndent(state, "", "  ")
+	// Create Temporal client with encryption and tracing
+	c, err := client.Dial(client.Options{
+		HostPort:      o.address,
+		Namespace:     o.namespace,
+		DataConverter: dataConverter,
+		Logger:        sdklog.NewStructuredLogger(logging.NewSlogLogger(os.Stderr, logOptions)),
+		Interceptors:  []interceptor.ClientInterceptor{tracingInterceptor},
+	})
 	if err != nil {
-		log.Fatalf("Failed to marshal state: %v", err)
+		return nil, fmt.Errorf("unable to create Temporal client: %w", err)
 	}
+	return c, nil
+}
+
+// target identifies the workflow a command acts on
+type target struct {
+	workflowID string
+	orderID    string
+	runID      string
+}
+
+// addTargetFlags adds the flags identifying the workflow
+func addTargetFlags(fs *flag.FlagSet) *target {
+	t := &target{}
+	fs.StringVar(&t.workflowID, "workflow-id", "", "Workflow ID")
+	fs.StringVar(&t.orderID, "order-id", "", "Order ID, instead of -workflow-id")
+	fs.StringVar(&t.runID, "run-id", "", "Run ID (default the latest run)")
+	return t
+}
 
-	log.Println("\nWorkflow State:")
-	fmt.Println(string(stateJSON))
+// resolve returns the workflow ID
+func (t *target) resolve() (string, error) {
+	switch {
+	case t.workflowID != "":
+		return t.workflowID, nil
+	case t.orderID != "":
+		return workflows.OrderWorkflowID(t.orderID), nil
+	default:
+		return "", usagef("-workflow-id or -order-id is required")
+	}
+}
+
+// envOr returns the environment variable, or fallback when it is unset
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
 )
 
@@ -21,19 +22,20 @@ func TestValidateOrder(t *testing.T) {
 		mockHandler   func(w http.ResponseWriter, r *http.Request)
 		wantErr       bool
 		errorContains string
+		wantErrType   string
 		verifyRequest bool
 	}{
 		{
 			name: "Success - Valid Order",
 			order: models.Order{
 				ID:     "TEST-001",
-				Amount: 500.0,
+				Amount: models.NewMoney(50000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Test Product",
 						Quantity:  1,
-						Price:     500.0,
+						Price:     models.NewMoney(50000, "USD"),
 					},
 				},
 			},
@@ -52,13 +54,13 @@ func TestValidateOrder(t *testing.T) {
 			name: "Failure - Validation Failed",
 			order: models.Order{
 				ID:     "TEST-002",
-				Amount: 15000.0,
+				Amount: models.NewMoney(1500000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Expensive Product",
 						Quantity:  1,
-						Price:     15000.0,
+						Price:     models.NewMoney(1500000, "USD"),
 					},
 				},
 			},
@@ -72,12 +74,13 @@ func TestValidateOrder(t *testing.T) {
 			},
 			wantErr:       true,
 			errorContains: "validation failed",
+			wantErrType:   activities.ErrTypeValidationFailed,
 		},
 		{
 			name: "Failure - Server Error",
 			order: models.Order{
 				ID:     "TEST-003",
-				Amount: 500.0,
+				Amount: models.NewMoney(50000, "USD"),
 			},
 			mockHandler: func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusInternalServerError)
@@ -90,7 +93,7 @@ func TestValidateOrder(t *testing.T) {
 			name: "Failure - Bad Gateway",
 			order: models.Order{
 				ID:     "TEST-004",
-				Amount: 750.0,
+				Amount: models.NewMoney(75000, "USD"),
 			},
 			mockHandler: func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusBadGateway)
@@ -103,13 +106,13 @@ func TestValidateOrder(t *testing.T) {
 			name: "Success - Large Order Within Limit",
 			order: models.Order{
 				ID:     "TEST-005",
-				Amount: 9999.0,
+				Amount: models.NewMoney(999900, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "High Value Product",
 						Quantity:  10,
-						Price:     999.9,
+						Price:     models.NewMoney(99990, "USD"),
 					},
 				},
 			},
@@ -161,6 +164,13 @@ func TestValidateOrder(t *testing.T) {
 				if tt.errorContains != "" {
 					assert.Contains(t, err.Error(), tt.errorContains)
 				}
+				// Only rejections are final; failures to reach the service are retried
+				var appErr *temporal.ApplicationError
+				require.ErrorAs(t, err, &appErr)
+				assert.Equal(t, tt.wantErrType != "", appErr.NonRetryable())
+				if tt.wantErrType != "" {
+					assert.Equal(t, tt.wantErrType, appErr.Type())
+				}
 			} else {
 				assert.NoError(t, err)
 			}
@@ -179,19 +189,19 @@ func TestProcessOrder(t *testing.T) {
 			name: "Success - Valid Order",
 			order: models.Order{
 				ID:     "TEST-004",
-				Amount: 1500.0,
+				Amount: models.NewMoney(150000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Product 1",
 						Quantity:  2,
-						Price:     500.0,
+						Price:     models.NewMoney(50000, "USD"),
 					},
 					{
 						ProductID: "PROD-002",
 						Name:      "Product 2",
 						Quantity:  1,
-						Price:     500.0,
+						Price:     models.NewMoney(50000, "USD"),
 					},
 				},
 			},
@@ -201,13 +211,13 @@ func TestProcessOrder(t *testing.T) {
 			name: "Success - Single Item",
 			order: models.Order{
 				ID:     "TEST-005",
-				Amount: 1000.0,
+				Amount: models.NewMoney(100000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Product 1",
 						Quantity:  2,
-						Price:     500.0,
+						Price:     models.NewMoney(50000, "USD"),
 					},
 				},
 			},
@@ -217,13 +227,13 @@ func TestProcessOrder(t *testing.T) {
 			name: "Failure - Amount Mismatch",
 			order: models.Order{
 				ID:     "TEST-006",
-				Amount: 1000.0,
+				Amount: models.NewMoney(100000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Product 1",
 						Quantity:  2,
-						Price:     600.0,
+						Price:     models.NewMoney(60000, "USD"),
 					},
 				},
 			},
@@ -234,25 +244,25 @@ func TestProcessOrder(t *testing.T) {
 			name: "Success - Multiple Items Complex",
 			order: models.Order{
 				ID:     "TEST-007",
-				Amount: 2500.0, // 3*250 + 2*500 + 5*150 = 750 + 1000 + 750 = 2500
+				Amount: models.NewMoney(250000, "USD"), // 3*250 + 2*500 + 5*150 = 750 + 1000 + 750 = 2500
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Product 1",
 						Quantity:  3,
-						Price:     250.0,
+						Price:     models.NewMoney(25000, "USD"),
 					},
 					{
 						ProductID: "PROD-002",
 						Name:      "Product 2",
 						Quantity:  2,
-						Price:     500.0,
+						Price:     models.NewMoney(50000, "USD"),
 					},
 					{
 						ProductID: "PROD-003",
 						Name:      "Product 3",
 						Quantity:  5,
-						Price:     150.0,
+						Price:     models.NewMoney(15000, "USD"),
 					},
 				},
 			},
@@ -262,7 +272,7 @@ func TestProcessOrder(t *testing.T) {
 			name: "Success - Empty Items",
 			order: models.Order{
 				ID:     "TEST-008",
-				Amount: 0.0,
+				Amount: models.NewMoney(0, "USD"),
 				Items:  []models.OrderItem{},
 			},
 			wantErr: false,
@@ -302,7 +312,7 @@ func TestNotifyCustomer(t *testing.T) {
 			name: "Success - Order Processed",
 			order: models.Order{
 				ID:     "TEST-009",
-				Amount: 500.0,
+				Amount: models.NewMoney(50000, "USD"),
 			},
 			message: "Order processed successfully",
 			wantErr: false,
@@ -311,7 +321,7 @@ func TestNotifyCustomer(t *testing.T) {
 			name: "Success - Order Cancelled",
 			order: models.Order{
 				ID:     "TEST-010",
-				Amount: 750.0,
+				Amount: models.NewMoney(75000, "USD"),
 			},
 			message: "Order has been cancelled",
 			wantErr: false,
@@ -320,7 +330,7 @@ func TestNotifyCustomer(t *testing.T) {
 			name: "Success - Payment Failed",
 			order: models.Order{
 				ID:     "TEST-011",
-				Amount: 1200.0,
+				Amount: models.NewMoney(120000, "USD"),
 			},
 			message: "Payment authorization failed",
 			wantErr: false,
@@ -329,7 +339,7 @@ func TestNotifyCustomer(t *testing.T) {
 			name: "Success - Empty Message",
 			order: models.Order{
 				ID:     "TEST-012",
-				Amount: 300.0,
+				Amount: models.NewMoney(30000, "USD"),
 			},
 			message: "",
 			wantErr: false,
@@ -365,7 +375,7 @@ func TestRollbackOrder(t *testing.T) {
 			name: "Success - Small Order",
 			order: models.Order{
 				ID:     "TEST-013",
-				Amount: 500.0,
+				Amount: models.NewMoney(50000, "USD"),
 			},
 			wantErr: false,
 		},
@@ -373,13 +383,13 @@ func TestRollbackOrder(t *testing.T) {
 			name: "Success - Large Order",
 			order: models.Order{
 				ID:     "TEST-014",
-				Amount: 5000.0,
+				Amount: models.NewMoney(500000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Product 1",
 						Quantity:  10,
-						Price:     500.0,
+						Price:     models.NewMoney(50000, "USD"),
 					},
 				},
 			},
@@ -389,7 +399,7 @@ func TestRollbackOrder(t *testing.T) {
 			name: "Success - Zero Amount",
 			order: models.Order{
 				ID:     "TEST-015",
-				Amount: 0.0,
+				Amount: models.NewMoney(0, "USD"),
 			},
 			wantErr: false,
 		},
@@ -397,19 +407,19 @@ func TestRollbackOrder(t *testing.T) {
 			name: "Success - Complex Order",
 			order: models.Order{
 				ID:     "TEST-016",
-				Amount: 3500.0,
+				Amount: models.NewMoney(350000, "USD"),
 				Items: []models.OrderItem{
 					{
 						ProductID: "PROD-001",
 						Name:      "Product 1",
 						Quantity:  5,
-						Price:     300.0,
+						Price:     models.NewMoney(30000, "USD"),
 					},
 					{
 						ProductID: "PROD-002",
 						Name:      "Product 2",
 						Quantity:  2,
-						Price:     1000.0,
+						Price:     models.NewMoney(100000, "USD"),
 					},
 				},
 			},
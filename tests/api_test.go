@@ -0,0 +1,421 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"temporal-order-system/api"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// apiErrorBody decodes the error response of the order API
+func apiErrorBody(t *testing.T, rec *httptest.ResponseRecorder) api.Error {
+	var body struct {
+		Error api.Error `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body.Error
+}
+
+func TestAPI_CreateOrder(t *testing.T) {
+	validOrder := `{"id": "ORD-1", "items": [{"product_id": "KB-01", "name": "Keyboard", "quantity": 2, "price": {"minor_units": 5000, "currency": "USD"}}]}`
+
+	tests := []struct {
+		name       string
+		body       string
+		startErr   error
+		wantStatus int
+		wantCode   string
+		wantAmount models.Money
+	}{
+		{
+			name:       "Success - Amount Computed From Items",
+			body:       validOrder,
+			wantStatus: http.StatusCreated,
+			wantAmount: models.NewMoney(10000, "USD"),
+		},
+		{
+			name:       "Success - Discount Code Applied",
+			body:       `{"id": "ORD-1", "discount_code": "SAVE10", "items": [{"product_id": "KB-01", "name": "Keyboard", "quantity": 1, "price": {"minor_units": 5000, "currency": "USD"}}]}`,
+			wantStatus: http.StatusCreated,
+			wantAmount: models.NewMoney(4500, "USD"),
+		},
+		{
+			name:       "Success - Amount Matches Items",
+			body:       `{"id": "ORD-1", "amount": {"minor_units": 10000, "currency": "USD"}, "items": [{"product_id": "KB-01", "name": "Keyboard", "quantity": 2, "price": {"minor_units": 5000, "currency": "USD"}}]}`,
+			wantStatus: http.StatusCreated,
+			wantAmount: models.NewMoney(10000, "USD"),
+		},
+		{
+			name:       "Failure - Malformed JSON",
+			body:       `{"id": `,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+		},
+		{
+			name:       "Failure - Unknown Field",
+			body:       `{"id": "ORD-1", "colour": "red"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+		},
+		{
+			name:       "Failure - No Items",
+			body:       `{"id": "ORD-1", "items": []}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_order",
+		},
+		{
+			name:       "Failure - Amount Differs From Items",
+			body:       `{"id": "ORD-1", "amount": {"minor_units": 9000, "currency": "USD"}, "items": [{"product_id": "KB-01", "name": "Keyboard", "quantity": 2, "price": {"minor_units": 5000, "currency": "USD"}}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_order",
+		},
+		{
+			name:       "Failure - Already Started",
+			body:       validOrder,
+			startErr:   serviceerror.NewWorkflowExecutionAlreadyStarted("already started", "", "run-0"),
+			wantStatus: http.StatusConflict,
+			wantCode:   "order_exists",
+		},
+		{
+			name:       "Failure - Temporal Unavailable",
+			body:       validOrder,
+			startErr:   serviceerror.NewUnavailable("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mocks.NewClient(t)
+			if tt.wantStatus == http.StatusCreated || tt.startErr != nil {
+				run := mocks.NewWorkflowRun(t)
+				if tt.startErr == nil {
+					run.On("GetID").Return("order-workflow-ORD-1")
+					run.On("GetRunID").Return("run-1")
+				}
+				c.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+					return o.ID == "order-workflow-ORD-1" && o.TaskQueue == "orders" && o.WorkflowExecutionErrorWhenAlreadyStarted
+				}), mock.Anything, mock.AnythingOfType("models.Order")).Return(run, tt.startErr)
+			}
+
+			server := api.NewServer(c, api.Options{TaskQueue: "orders"})
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, apiErrorBody(t, rec).Code)
+				return
+			}
+
+			var created api.OrderCreated
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+			assert.Equal(t, "ORD-1", created.OrderID)
+			assert.Equal(t, "run-1", created.RunID)
+			assert.Equal(t, tt.wantAmount, created.Amount)
+			assert.Equal(t, "/orders/ORD-1", rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestAPI_GetOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		queryErr   error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "Success - State Returned",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Failure - Not Found",
+			queryErr:   serviceerror.NewNotFound("workflow not found"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "Failure - Unexpected Error Hidden",
+			queryErr:   errors.New("secret internals"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := models.WorkflowState{OrderID: "ORD-1", Status: models.OrderStatusValidated, ValidationDone: true}
+			value := mocks.NewEncodedValue(t)
+			if tt.queryErr == nil {
+				value.On("Get", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*models.WorkflowState) = state
+				}).Return(nil)
+			}
+
+			c := mocks.NewClient(t)
+			c.On("QueryWorkflow", mock.Anything, "order-workflow-ORD-1", "", "state").Return(value, tt.queryErr)
+
+			rec := httptest.NewRecorder()
+			api.NewServer(c, api.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/ORD-1", nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantCode != "" {
+				body := apiErrorBody(t, rec)
+				assert.Equal(t, tt.wantCode, body.Code)
+				assert.NotContains(t, body.Message, "secret")
+				return
+			}
+
+			var got models.WorkflowState
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, state, got)
+		})
+	}
+}
+
+func TestAPI_SignalOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		signal     string
+		signalErr  error
+		wantStatus int
+	}{
+		{
+			name:       "Success - Cancel",
+			path:       "/orders/ORD-1/cancel",
+			signal:     "cancel",
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "Success - Expedite",
+			path:       "/orders/ORD-1/expedite",
+			signal:     "expedite",
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "Failure - Workflow Closed",
+			path:       "/orders/ORD-1/cancel",
+			signal:     "cancel",
+			signalErr:  serviceerror.NewNotFound("workflow execution already completed"),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Failure - Unknown Signal",
+			path:       "/orders/ORD-1/refund",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mocks.NewClient(t)
+			if tt.signal != "" {
+				c.On("SignalWorkflow", mock.Anything, "order-workflow-ORD-1", "", tt.signal, tt.signal).Return(tt.signalErr)
+			}
+
+			rec := httptest.NewRecorder()
+			api.NewServer(c, api.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusAccepted {
+				var sent api.SignalSent
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sent))
+				assert.Equal(t, tt.signal, sent.Signal)
+			}
+		})
+	}
+}
+
+func TestAPI_ListOrders(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantQuery  string
+		wantSize   int32
+		wantStatus int
+	}{
+		{
+			name:       "Success - Defaults",
+			url:        "/orders",
+			wantQuery:  "WorkflowType = 'OrderWorkflow'",
+			wantSize:   api.DefaultPageSize,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Success - Status Filter",
+			url:        "/orders?status=Running&page_size=5",
+			wantQuery:  "WorkflowType = 'OrderWorkflow' AND ExecutionStatus = 'Running'",
+			wantSize:   5,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Failure - Unknown Status",
+			url:        "/orders?status=Running'%20OR%20'1'='1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Failure - Page Size Too Large",
+			url:        "/orders?page_size=1000",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mocks.NewClient(t)
+			if tt.wantStatus == http.StatusOK {
+				c.On("ListWorkflow", mock.Anything, mock.MatchedBy(func(r *workflowservice.ListWorkflowExecutionsRequest) bool {
+					return r.GetQuery() == tt.wantQuery && r.GetPageSize() == tt.wantSize
+				})).Return(&workflowservice.ListWorkflowExecutionsResponse{
+					Executions: []*workflowpb.WorkflowExecutionInfo{{
+						Execution: &commonpb.WorkflowExecution{WorkflowId: "order-workflow-ORD-1", RunId: "run-1"},
+						Status:    enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING,
+						StartTime: timestamppb.New(started),
+					}},
+					NextPageToken: []byte("next"),
+				}, nil)
+			}
+
+			rec := httptest.NewRecorder()
+			api.NewServer(c, api.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, "invalid_request", apiErrorBody(t, rec).Code)
+				return
+			}
+
+			var list api.OrderList
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+			require.Len(t, list.Orders, 1)
+			assert.Equal(t, "ORD-1", list.Orders[0].OrderID)
+			assert.Equal(t, "Running", list.Orders[0].Status)
+			assert.True(t, started.Equal(list.Orders[0].StartTime))
+			assert.NotEmpty(t, list.NextPageToken)
+		})
+	}
+}
+
+func TestAPI_StreamOrder(t *testing.T) {
+	// progressHandle returns an update handle whose result is progress
+	progressHandle := func(t *testing.T, progress models.OrderProgress) *mocks.WorkflowUpdateHandle {
+		handle := mocks.NewWorkflowUpdateHandle(t)
+		handle.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*models.OrderProgress) = progress
+		}).Return(nil)
+		return handle
+	}
+	waitingFor := func(version int64) interface{} {
+		return mock.MatchedBy(func(o client.UpdateWorkflowOptions) bool {
+			wait, ok := o.Args[0].(workflows.ChangeWait)
+			return o.WorkflowID == "order-workflow-ORD-1" && o.UpdateName == workflows.UpdateWaitForChange && ok && wait.Version == version
+		})
+	}
+
+	t.Run("Success - Progress Until Final", func(t *testing.T) {
+		c := mocks.NewClient(t)
+		c.On("UpdateWorkflow", mock.Anything, waitingFor(-1)).Return(progressHandle(t, models.OrderProgress{
+			State: models.WorkflowState{OrderID: "ORD-1", Status: models.OrderStatusPending, Version: 1},
+		}), nil).Once()
+		c.On("UpdateWorkflow", mock.Anything, waitingFor(1)).Return(progressHandle(t, models.OrderProgress{
+			State: models.WorkflowState{OrderID: "ORD-1", Status: models.OrderStatusCompleted, Version: 9},
+			Final: true,
+		}), nil).Once()
+
+		rec := httptest.NewRecorder()
+		api.NewServer(c, api.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/ORD-1/events", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+		require.Len(t, events, 3)
+		assert.Contains(t, events[0], "id: 1\nevent: progress\ndata: ")
+		assert.Contains(t, events[1], "id: 9\nevent: progress\ndata: ")
+		assert.Contains(t, events[1], `"final":true`)
+		assert.Equal(t, "event: end\ndata: {}", events[2])
+	})
+
+	t.Run("Success - Resumes After Last Event ID", func(t *testing.T) {
+		c := mocks.NewClient(t)
+		c.On("UpdateWorkflow", mock.Anything, waitingFor(4)).Return(progressHandle(t, models.OrderProgress{
+			State: models.WorkflowState{OrderID: "ORD-1", Status: models.OrderStatusCompleted, Version: 6},
+			Final: true,
+		}), nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/orders/ORD-1/events", nil)
+		req.Header.Set("Last-Event-ID", "4")
+		rec := httptest.NewRecorder()
+		api.NewServer(c, api.Options{}).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "id: 6\n")
+	})
+
+	t.Run("Failure - Too Many Watchers", func(t *testing.T) {
+		watching := make(chan struct{})
+		release := make(chan struct{})
+		c := mocks.NewClient(t)
+		c.On("UpdateWorkflow", mock.Anything, waitingFor(-1)).Run(func(mock.Arguments) {
+			close(watching)
+			<-release
+		}).Return(progressHandle(t, models.OrderProgress{
+			State: models.WorkflowState{OrderID: "ORD-1", Status: models.OrderStatusCompleted, Version: 9},
+			Final: true,
+		}), nil).Once()
+		server := api.NewServer(c, api.Options{MaxWatchersPerOrder: 1})
+
+		first := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			server.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/orders/ORD-1/events", nil))
+		}()
+		<-watching
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/ORD-1/events", nil))
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "too_many_watchers", apiErrorBody(t, rec).Code)
+
+		close(release)
+		<-done
+		assert.Equal(t, http.StatusOK, first.Code)
+	})
+
+	t.Run("Failure - Not Found Before Streaming", func(t *testing.T) {
+		c := mocks.NewClient(t)
+		c.On("UpdateWorkflow", mock.Anything, waitingFor(-1)).Return(nil, serviceerror.NewNotFound("workflow not found"))
+		c.On("DescribeWorkflowExecution", mock.Anything, "order-workflow-ORD-1", "").Return(nil, serviceerror.NewNotFound("workflow not found"))
+
+		rec := httptest.NewRecorder()
+		api.NewServer(c, api.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/ORD-1/events", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "not_found", apiErrorBody(t, rec).Code)
+	})
+}
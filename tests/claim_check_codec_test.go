@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+// newS3StandIn serves path-style PUT and GET object requests from memory
+func newS3StandIn(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClaimCheckCodec(t *testing.T) {
+	fileStore, err := codec.NewFileBlobStore(t.TempDir())
+	require.NoError(t, err)
+	s3Store := codec.NewS3BlobStore(codec.NewHTTPObjectClient(newS3StandIn(t).URL), "orders", "payloads/")
+
+	stores := []struct {
+		name  string
+		store codec.BlobStore
+	}{
+		{name: "File", store: fileStore},
+		{name: "S3", store: s3Store},
+	}
+
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			dataConverter, err := codec.NewDataConverterBuilder().
+				WithKey(newKey).
+				WithoutCompression().
+				WithClaimCheck(s.store, 4096).
+				Build()
+			require.NoError(t, err)
+
+			// Large orders are replaced by a reference
+			order := largeOrder(100)
+			payload, err := dataConverter.ToPayload(order)
+			require.NoError(t, err)
+			assert.Equal(t, codec.MetadataEncodingClaimCheck, string(payload.Metadata["encoding"]))
+			assert.Len(t, payload.Data, 64)
+
+			// The offloaded bytes are the encrypted payload
+			blob, err := s.store.Get(context.Background(), string(payload.Data))
+			require.NoError(t, err)
+			assert.NotContains(t, string(blob), "Sample Product")
+
+			var got models.Order
+			require.NoError(t, dataConverter.FromPayload(payload, &got))
+			assert.Equal(t, order, got)
+
+			// Small orders stay inline
+			small, err := dataConverter.ToPayload(largeOrder(1))
+			require.NoError(t, err)
+			assert.Equal(t, codec.MetadataEncodingEncrypted, string(small.Metadata["encoding"]))
+		})
+	}
+}
+
+func TestClaimCheckCodec_DecodeFailures(t *testing.T) {
+	dir := t.TempDir()
+	store, err := codec.NewFileBlobStore(dir)
+	require.NoError(t, err)
+	claimCheck := codec.NewClaimCheckCodec(store, 0)
+
+	encoded, err := claimCheck.Encode([]*commonpb.Payload{jsonPayload(`{"id":"TEST-CC-001"}`)})
+	require.NoError(t, err)
+	key := string(encoded[0].Data)
+
+	tests := []struct {
+		name          string
+		key           string
+		setup         func(t *testing.T)
+		errorContains string
+	}{
+		{
+			name:          "Missing Blob",
+			key:           strings.Repeat("0", 64),
+			errorContains: "blob not found",
+		},
+		{
+			name: "Tampered Blob",
+			key:  key,
+			setup: func(t *testing.T) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, key), []byte("tampered"), 0o644))
+			},
+			errorContains: "does not match its key",
+		},
+		{
+			name:          "Key Outside Store",
+			key:           "../" + key,
+			errorContains: "invalid blob key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			payload := &commonpb.Payload{
+				Metadata: map[string][]byte{converter.MetadataEncoding: []byte(codec.MetadataEncodingClaimCheck)},
+				Data:     []byte(tt.key),
+			}
+			_, err := claimCheck.Decode([]*commonpb.Payload{payload})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorContains)
+		})
+	}
+}
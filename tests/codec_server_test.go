@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"temporal-order-system/codec"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+func TestCodecServer(t *testing.T) {
+	defaultCodec := mustCodec(t, codec.DefaultKeyID, map[string][]byte{codec.DefaultKeyID: oldKey})
+	ordersCodec := mustCodec(t, "orders-1", map[string][]byte{"orders-1": newKey})
+
+	codecServer, err := codec.NewCodecServer(codec.CodecServerOptions{
+		Codecs:         map[string][]converter.PayloadCodec{"orders": {ordersCodec}},
+		DefaultCodecs:  []converter.PayloadCodec{defaultCodec},
+		AuthToken:      "secret",
+		AllowedOrigins: []string{"http://localhost:8080"},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(codecServer)
+	defer server.Close()
+
+	remote := func(namespace, token string) converter.PayloadCodec {
+		return converter.NewRemotePayloadCodec(converter.RemotePayloadCodecOptions{
+			Endpoint: server.URL,
+			ModifyRequest: func(r *http.Request) error {
+				r.Header.Set(codec.NamespaceHeader, namespace)
+				if token != "" {
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+				return nil
+			},
+		})
+	}
+
+	ordersPayloads, err := ordersCodec.Encode([]*commonpb.Payload{jsonPayload(`"orders"`)})
+	require.NoError(t, err)
+	defaultPayloads, err := defaultCodec.Encode([]*commonpb.Payload{jsonPayload(`"default"`)})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		namespace     string
+		token         string
+		payloads      []*commonpb.Payload
+		wantData      string
+		errorContains string
+	}{
+		{
+			name:      "Success - Namespace Keyring",
+			namespace: "orders",
+			token:     "secret",
+			payloads:  ordersPayloads,
+			wantData:  `"orders"`,
+		},
+		{
+			name:      "Success - Default Keyring For Other Namespaces",
+			namespace: "default",
+			token:     "secret",
+			payloads:  defaultPayloads,
+			wantData:  `"default"`,
+		},
+		{
+			name:          "Failure - Keys Are Scoped To Their Namespace",
+			namespace:     "default",
+			token:         "secret",
+			payloads:      ordersPayloads,
+			errorContains: "unknown encryption key id",
+		},
+		{
+			name:          "Failure - Missing Token",
+			namespace:     "orders",
+			payloads:      ordersPayloads,
+			errorContains: "Unauthorized",
+		},
+		{
+			name:          "Failure - Wrong Token",
+			namespace:     "orders",
+			token:         "guess",
+			payloads:      ordersPayloads,
+			errorContains: "Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := remote(tt.namespace, tt.token).Decode(tt.payloads)
+			if tt.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, string(decoded[0].Data))
+		})
+	}
+
+	t.Run("Success - Encode Uses Namespace Key", func(t *testing.T) {
+		encoded, err := remote("orders", "secret").Encode([]*commonpb.Payload{jsonPayload(`"remote"`)})
+		require.NoError(t, err)
+		assert.Equal(t, "orders-1", string(encoded[0].Metadata[codec.MetadataEncryptionKeyID]))
+
+		decoded, err := ordersCodec.Decode(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, `"remote"`, string(decoded[0].Data))
+	})
+
+	t.Run("Success - CORS Preflight Without Token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodOptions, server.URL+"/decode", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://localhost:8080")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "http://localhost:8080", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), codec.NamespaceHeader)
+	})
+
+	t.Run("Failure - Origin Not Allowed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/decode", strings.NewReader(`{"payloads":[]}`))
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://evil.example")
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Failure - Unknown Namespace Without Default", func(t *testing.T) {
+		scopedServer, err := codec.NewCodecServer(codec.CodecServerOptions{
+			Codecs:      map[string][]converter.PayloadCodec{"orders": {ordersCodec}},
+			DisableAuth: true,
+		})
+		require.NoError(t, err)
+		scoped := httptest.NewServer(scopedServer)
+		defer scoped.Close()
+
+		_, err = converter.NewRemotePayloadCodec(converter.RemotePayloadCodecOptions{
+			Endpoint: scoped.URL,
+			ModifyRequest: func(r *http.Request) error {
+				r.Header.Set(codec.NamespaceHeader, "billing")
+				return nil
+			},
+		}).Decode(ordersPayloads)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no codec for namespace billing")
+	})
+
+	t.Run("Success - Wildcard Origin Without Credentials", func(t *testing.T) {
+		wildcardServer, err := codec.NewCodecServer(codec.CodecServerOptions{
+			DefaultCodecs:  []converter.PayloadCodec{defaultCodec},
+			AuthToken:      "secret",
+			AllowedOrigins: []string{"*"},
+		})
+		require.NoError(t, err)
+		wildcard := httptest.NewServer(wildcardServer)
+		defer wildcard.Close()
+
+		req, err := http.NewRequest(http.MethodOptions, wildcard.URL+"/decode", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://evil.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("Failure - No Token Unless Auth Is Disabled", func(t *testing.T) {
+		_, err := codec.NewCodecServer(codec.CodecServerOptions{
+			DefaultCodecs: []converter.PayloadCodec{defaultCodec},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "needs an auth token")
+	})
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// largeOrder builds an order with the given number of items
+func largeOrder(items int) models.Order {
+	order := models.Order{
+		ID:     fmt.Sprintf("ORD-BENCH-%d", items),
+		Status: models.OrderStatusPending,
+		ShippingAddress: &models.Address{
+			Line1:      "1 Market Street",
+			City:       "San Francisco",
+			PostalCode: "94105",
+			Country:    "US",
+		},
+	}
+	for i := 0; i < items; i++ {
+		order.Items = append(order.Items, models.OrderItem{
+			ProductID: fmt.Sprintf("PROD-%05d", i),
+			Name:      fmt.Sprintf("Sample Product %d", i),
+			Quantity:  i%5 + 1,
+			Price:     models.NewMoney(int64(1000+i*25), "USD"),
+		})
+	}
+	if err := order.Reprice(); err != nil {
+		panic(err)
+	}
+	return order
+}
+
+func TestCompressionCodec(t *testing.T) {
+	compression := codec.NewCompressionCodec(codec.DefaultCompressionThreshold)
+
+	small, err := converter.GetDefaultDataConverter().ToPayload(largeOrder(1))
+	require.NoError(t, err)
+	large, err := converter.GetDefaultDataConverter().ToPayload(largeOrder(100))
+	require.NoError(t, err)
+	random := &commonpb.Payload{
+		Metadata: map[string][]byte{"encoding": []byte("binary/plain")},
+		Data:     make([]byte, 4096),
+	}
+	_, err = rand.Read(random.Data)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		payload      *commonpb.Payload
+		wantEncoding string
+	}{
+		{name: "Below Threshold - Unchanged", payload: small, wantEncoding: converter.MetadataEncodingJSON},
+		{name: "Above Threshold - Compressed", payload: large, wantEncoding: codec.MetadataEncodingGzip},
+		{name: "Incompressible - Unchanged", payload: random, wantEncoding: "binary/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := compression.Encode([]*commonpb.Payload{tt.payload})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEncoding, string(encoded[0].Metadata["encoding"]))
+			if tt.wantEncoding == codec.MetadataEncodingGzip {
+				assert.Less(t, proto.Size(encoded[0]), proto.Size(tt.payload))
+			}
+
+			decoded, err := compression.Decode(encoded)
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(tt.payload, decoded[0]))
+		})
+	}
+}
+
+func TestCompressionCodec_DecompressedSizeLimit(t *testing.T) {
+	payload, err := converter.GetDefaultDataConverter().ToPayload(largeOrder(100))
+	require.NoError(t, err)
+	size := proto.Size(payload)
+
+	tests := []struct {
+		name    string
+		limit   int
+		wantErr bool
+	}{
+		{name: "Success - Exactly The Limit", limit: size},
+		{name: "Failure - Larger Than The Limit", limit: size - 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compression := codec.NewCompressionCodecWithLimit(codec.DefaultCompressionThreshold, tt.limit)
+			encoded, err := compression.Encode([]*commonpb.Payload{payload})
+			require.NoError(t, err)
+			require.Equal(t, codec.MetadataEncodingGzip, string(encoded[0].Metadata["encoding"]))
+
+			decoded, err := compression.Decode(encoded)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), fmt.Sprintf("larger than %d bytes", tt.limit))
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(payload, decoded[0]))
+		})
+	}
+}
+
+func TestDataConverterBuilder_CompressesBeforeEncrypting(t *testing.T) {
+	dataConverter, err := codec.NewDataConverterBuilder().WithKey(newKey).Build()
+	require.NoError(t, err)
+
+	order := largeOrder(100)
+	plain, err := converter.GetDefaultDataConverter().ToPayload(order)
+	require.NoError(t, err)
+
+	payload, err := dataConverter.ToPayload(order)
+	require.NoError(t, err)
+	assert.Equal(t, codec.MetadataEncodingEncrypted, string(payload.Metadata["encoding"]))
+	assert.Less(t, proto.Size(payload), proto.Size(plain)/2, "ciphertext should hold the compressed order")
+
+	// Decrypting alone yields the compressed payload, so compression ran first
+	encryption, err := codec.NewEncryptionCodec(newKey)
+	require.NoError(t, err)
+	decrypted, err := encryption.Decode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+	assert.Equal(t, codec.MetadataEncodingGzip, string(decrypted[0].Metadata["encoding"]))
+
+	var got models.Order
+	require.NoError(t, dataConverter.FromPayload(payload, &got))
+	assert.Equal(t, order, got)
+
+	_, err = codec.NewDataConverterBuilder().Build()
+	assert.Error(t, err)
+}
+
+func BenchmarkDataConverter_Order(b *testing.B) {
+	converters := []struct {
+		name    string
+		builder *codec.DataConverterBuilder
+	}{
+		{name: "Encrypted", builder: codec.NewDataConverterBuilder().WithKey(newKey).WithoutCompression()},
+		{name: "Compressed And Encrypted", builder: codec.NewDataConverterBuilder().WithKey(newKey)},
+	}
+
+	for _, items := range []int{10, 100, 1000} {
+		order := largeOrder(items)
+		for _, c := range converters {
+			dataConverter, err := c.builder.Build()
+			require.NoError(b, err)
+
+			b.Run(fmt.Sprintf("%d Items/%s", items, c.name), func(b *testing.B) {
+				var size int
+				for i := 0; i < b.N; i++ {
+					payload, err := dataConverter.ToPayload(order)
+					if err != nil {
+						b.Fatal(err)
+					}
+					var got models.Order
+					if err := dataConverter.FromPayload(payload, &got); err != nil {
+						b.Fatal(err)
+					}
+					size = proto.Size(payload)
+				}
+				b.ReportMetric(float64(size), "payload-bytes")
+			})
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{0x01}, 32)
+	newKey = bytes.Repeat([]byte{0x02}, 32)
+)
+
+func jsonPayload(data string) *commonpb.Payload {
+	return &commonpb.Payload{
+		Metadata: map[string][]byte{"encoding": []byte("json/plain")},
+		Data:     []byte(data),
+	}
+}
+
+func mustKeyring(t *testing.T, activeID string, keys map[string][]byte) *codec.Keyring {
+	keyring, err := codec.NewKeyring(activeID, keys)
+	require.NoError(t, err)
+	return keyring
+}
+
+func mustCodec(t *testing.T, activeID string, keys map[string][]byte) *codec.EncryptionCodec {
+	return codec.NewKeyringEncryptionCodec(mustKeyring(t, activeID, keys))
+}
+
+func TestEncryptionCodec_KeyRotation(t *testing.T) {
+	before := codec.NewKeyringEncryptionCodec(mustKeyring(t, "2024-01", map[string][]byte{"2024-01": oldKey}))
+	after := codec.NewKeyringEncryptionCodec(mustKeyring(t, "2024-06", map[string][]byte{"2024-01": oldKey, "2024-06": newKey}))
+	legacy, err := codec.NewEncryptionCodec(oldKey)
+	require.NoError(t, err)
+
+	encodedBefore, err := before.Encode([]*commonpb.Payload{jsonPayload(`"before"`)})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", string(encodedBefore[0].Metadata[codec.MetadataEncryptionKeyID]))
+
+	encodedAfter, err := after.Encode([]*commonpb.Payload{jsonPayload(`"after"`)})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06", string(encodedAfter[0].Metadata[codec.MetadataEncryptionKeyID]))
+	assert.Equal(t, codec.MetadataEncodingEncrypted, string(encodedAfter[0].Metadata["encoding"]))
+
+	// Payloads encrypted before key IDs were written carry no key ID
+	encodedLegacy, err := legacy.Encode([]*commonpb.Payload{jsonPayload(`"legacy"`)})
+	require.NoError(t, err)
+	delete(encodedLegacy[0].Metadata, codec.MetadataEncryptionKeyID)
+
+	tests := []struct {
+		name          string
+		codec         *codec.EncryptionCodec
+		payload       *commonpb.Payload
+		wantData      string
+		errorContains string
+	}{
+		{
+			name:     "Success - Old Key Still Decrypts After Rotation",
+			codec:    after,
+			payload:  encodedBefore[0],
+			wantData: `"before"`,
+		},
+		{
+			name:     "Success - Active Key",
+			codec:    after,
+			payload:  encodedAfter[0],
+			wantData: `"after"`,
+		},
+		{
+			name:     "Success - Payload Without Key ID Uses Default Key",
+			codec:    mustCodec(t, codec.DefaultKeyID, map[string][]byte{codec.DefaultKeyID: oldKey, "2024-06": newKey}),
+			payload:  encodedLegacy[0],
+			wantData: `"legacy"`,
+		},
+		{
+			name:     "Success - Payload Without Key ID Uses Active Key Without Default",
+			codec:    before,
+			payload:  encodedLegacy[0],
+			wantData: `"legacy"`,
+		},
+		{
+			name:          "Failure - Key Removed From Keyring",
+			codec:         before,
+			payload:       encodedAfter[0],
+			errorContains: "unknown encryption key id",
+		},
+		{
+			name:          "Failure - Wrong Key For ID",
+			codec:         mustCodec(t, "2024-06", map[string][]byte{"2024-06": oldKey}),
+			payload:       encodedAfter[0],
+			errorContains: "failed to decrypt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := tt.codec.Decode([]*commonpb.Payload{tt.payload})
+			if tt.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, string(decoded[0].Data))
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name          string
+		activeID      string
+		keys          map[string][]byte
+		errorContains string
+	}{
+		{name: "Success - Active And Old Keys", activeID: "b", keys: map[string][]byte{"a": oldKey, "b": newKey}},
+		{name: "Failure - Active Key Missing", activeID: "c", keys: map[string][]byte{"a": oldKey}, errorContains: "not in the keyring"},
+		{name: "Failure - Short Key", activeID: "a", keys: map[string][]byte{"a": oldKey[:16]}, errorContains: "must be 32 bytes"},
+		{name: "Failure - Empty Key ID", activeID: "a", keys: map[string][]byte{"a": oldKey, "": newKey}, errorContains: "must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := codec.NewKeyring(tt.activeID, tt.keys)
+			if tt.errorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			activeID, key := keyring.ActiveKey()
+			assert.Equal(t, tt.activeID, activeID)
+			assert.Equal(t, tt.keys[tt.activeID], key)
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	oldHex, newHex := hex.EncodeToString(oldKey), hex.EncodeToString(newKey)
+
+	keyringPath := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, os.WriteFile(keyringPath, []byte(`{"active": "2024-06", "keys": {"2024-01": "`+oldHex+`", "2024-06": "`+newHex+`"}}`), 0o600))
+
+	tests := []struct {
+		name          string
+		env           map[string]string
+		wantActiveID  string
+		wantIDs       []string
+		wantErr       error
+		errorContains string
+	}{
+		{
+			name:         "Success - Single Key Gets Default ID",
+			env:          map[string]string{"ENCRYPTION_KEY": newHex},
+			wantActiveID: codec.DefaultKeyID,
+			wantIDs:      []string{codec.DefaultKeyID},
+		},
+		{
+			name: "Success - Active And Old Keys From Env",
+			env: map[string]string{
+				"ENCRYPTION_KEY":      newHex,
+				"ENCRYPTION_KEY_ID":   "2024-06",
+				"ENCRYPTION_OLD_KEYS": "2024-01:" + oldHex + ", default:" + oldHex,
+			},
+			wantActiveID: "2024-06",
+			wantIDs:      []string{"2024-01", "2024-06", codec.DefaultKeyID},
+		},
+		{
+			name: "Success - File Takes Precedence",
+			env: map[string]string{
+				"ENCRYPTION_KEYRING_FILE": keyringPath,
+				"ENCRYPTION_KEY":          oldHex,
+			},
+			wantActiveID: "2024-06",
+			wantIDs:      []string{"2024-01", "2024-06"},
+		},
+		{
+			name:    "Failure - Nothing Configured",
+			env:     map[string]string{},
+			wantErr: codec.ErrNoEncryptionKey,
+		},
+		{
+			name:          "Failure - Malformed Old Key",
+			env:           map[string]string{"ENCRYPTION_KEY": newHex, "ENCRYPTION_OLD_KEYS": oldHex},
+			errorContains: "must be formatted as id:hex",
+		},
+		{
+			name:          "Failure - Old Key Reuses Active ID",
+			env:           map[string]string{"ENCRYPTION_KEY": newHex, "ENCRYPTION_OLD_KEYS": "default:" + oldHex},
+			errorContains: "duplicate key id",
+		},
+		{
+			name:          "Failure - Missing File",
+			env:           map[string]string{"ENCRYPTION_KEYRING_FILE": filepath.Join(t.TempDir(), "missing.json")},
+			errorContains: "failed to read keyring file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ENCRYPTION_KEYRING_FILE", "ENCRYPTION_KEY", "ENCRYPTION_KEY_ID", "ENCRYPTION_OLD_KEYS"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			keyring, err := codec.LoadKeyring()
+			if tt.wantErr != nil || tt.errorContains != "" {
+				require.Error(t, err)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			activeID, _ := keyring.ActiveKey()
+			assert.Equal(t, tt.wantActiveID, activeID)
+			assert.Equal(t, tt.wantIDs, keyring.IDs())
+		})
+	}
+}
+
+func TestEncryptionCodec_PreservesPayloads(t *testing.T) {
+	encryption, err := codec.NewEncryptionCodec(newKey)
+	require.NoError(t, err)
+	dataConverter := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), encryption)
+
+	execution := &commonpb.WorkflowExecution{WorkflowId: "order-workflow-TEST-ENC-001", RunId: "run-1"}
+	order := models.Order{ID: "TEST-ENC-001", Amount: models.NewMoney(1000, "USD")}
+
+	tests := []struct {
+		name         string
+		converter    converter.PayloadConverter
+		value        interface{}
+		target       interface{}
+		want         interface{}
+		wantEncoding string
+	}{
+		{
+			name:         "Nil",
+			converter:    converter.NewNilPayloadConverter(),
+			value:        nil,
+			target:       &[]string{"not", "nil"},
+			want:         new([]string),
+			wantEncoding: converter.MetadataEncodingNil,
+		},
+		{
+			name:         "Byte Slice",
+			converter:    converter.NewByteSlicePayloadConverter(),
+			value:        []byte{0x00, 0xff, 0x10},
+			target:       new([]byte),
+			want:         &[]byte{0x00, 0xff, 0x10},
+			wantEncoding: converter.MetadataEncodingBinary,
+		},
+		{
+			name:         "Proto JSON",
+			converter:    converter.NewProtoJSONPayloadConverter(),
+			value:        execution,
+			target:       &commonpb.WorkflowExecution{},
+			want:         execution,
+			wantEncoding: converter.MetadataEncodingProtoJSON,
+		},
+		{
+			name:         "Proto",
+			converter:    converter.NewProtoPayloadConverter(),
+			value:        execution,
+			target:       &commonpb.WorkflowExecution{},
+			want:         execution,
+			wantEncoding: converter.MetadataEncodingProto,
+		},
+		{
+			name:         "JSON",
+			converter:    converter.NewJSONPayloadConverter(),
+			value:        order,
+			target:       &models.Order{},
+			want:         &order,
+			wantEncoding: converter.MetadataEncodingJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, err := tt.converter.ToPayload(tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantEncoding, string(original.Metadata[converter.MetadataEncoding]))
+
+			encoded, err := encryption.Encode([]*commonpb.Payload{original})
+			require.NoError(t, err)
+			assert.Equal(t, codec.MetadataEncodingEncrypted, string(encoded[0].Metadata[converter.MetadataEncoding]))
+			assert.NotContains(t, encoded[0].Metadata, converter.MetadataMessageType)
+
+			decoded, err := encryption.Decode(encoded)
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(original, decoded[0]), "got %v, want %v", decoded[0], original)
+
+			// Values sent through the encrypting data converter come back unchanged
+			payload, err := dataConverter.ToPayload(tt.value)
+			require.NoError(t, err)
+			require.NoError(t, dataConverter.FromPayload(payload, tt.target))
+			if want, ok := tt.want.(proto.Message); ok {
+				assert.True(t, proto.Equal(want, tt.target.(proto.Message)), "got %v, want %v", tt.target, want)
+			} else {
+				assert.Equal(t, tt.want, tt.target)
+			}
+		})
+	}
+}
+
+func TestEncryptionCodec_DecodesDataOnlyPayloads(t *testing.T) {
+	encryption, err := codec.NewEncryptionCodec(newKey)
+	require.NoError(t, err)
+
+	// Before the whole payload was encrypted, the ciphertext held only the data of a JSON payload
+	block, err := aes.NewCipher(newKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	payload := &commonpb.Payload{
+		Metadata: map[string][]byte{"encoding": []byte(codec.MetadataEncodingEncrypted)},
+		Data:     gcm.Seal(nonce, nonce, []byte(`{"id":"TEST-ENC-002"}`), nil),
+	}
+
+	decoded, err := encryption.Decode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+	assert.Equal(t, "json/plain", string(decoded[0].Metadata["encoding"]))
+	assert.Equal(t, `{"id":"TEST-ENC-002"}`, string(decoded[0].Data))
+}
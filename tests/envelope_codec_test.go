@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+)
+
+func TestEnvelopeCodec(t *testing.T) {
+	masterKeys := mustKeyring(t, "master-2", map[string][]byte{"master-1": oldKey, "master-2": newKey})
+	kms := httptest.NewServer(codec.NewKMSHandler(codec.NewKeyringKeyProvider(masterKeys)))
+	defer kms.Close()
+
+	providers := []struct {
+		name     string
+		provider codec.KeyProvider
+	}{
+		{name: "Keyring", provider: codec.NewKeyringKeyProvider(masterKeys)},
+		{name: "HTTP KMS", provider: codec.NewHTTPKeyProvider(kms.URL)},
+	}
+
+	for _, p := range providers {
+		t.Run(p.name, func(t *testing.T) {
+			provider := &countingKeyProvider{KeyProvider: p.provider}
+			envelope := codec.NewEnvelopeCodec(provider)
+
+			original := []*commonpb.Payload{jsonPayload(`"first"`), jsonPayload(`"second"`)}
+			encoded, err := envelope.Encode(original)
+			require.NoError(t, err)
+
+			for _, payload := range encoded {
+				assert.Equal(t, codec.MetadataEncodingEnvelope, string(payload.Metadata["encoding"]))
+				assert.Equal(t, "master-2", string(payload.Metadata[codec.MetadataEncryptionKeyID]))
+				assert.NotEmpty(t, payload.Metadata[codec.MetadataEncryptionDataKey])
+			}
+			// Payloads share the data key until it is rotated
+			assert.Equal(t, encoded[0].Metadata[codec.MetadataEncryptionDataKey], encoded[1].Metadata[codec.MetadataEncryptionDataKey])
+			assert.NotEqual(t, encoded[0].Data, encoded[1].Data)
+			assert.Equal(t, int32(1), provider.generated.Load())
+
+			// Another codec of the same provider unwraps the data key once
+			reader := &countingKeyProvider{KeyProvider: p.provider}
+			decoded, err := codec.NewEnvelopeCodec(reader).Decode(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, `"first"`, string(decoded[0].Data))
+			assert.Equal(t, `"second"`, string(decoded[1].Data))
+			assert.Equal(t, int32(1), reader.decrypted.Load())
+
+			// Master keys are rotated like any keyring; removed keys no longer unwrap
+			retired := codec.NewEnvelopeCodec(codec.NewKeyringKeyProvider(mustKeyring(t, "master-1", map[string][]byte{"master-1": oldKey})))
+			_, err = retired.Decode(encoded)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unknown encryption key id")
+		})
+	}
+}
+
+func TestDataConverterBuilder_KeyProviderDecodesKeyringPayloads(t *testing.T) {
+	keyring := mustKeyring(t, codec.DefaultKeyID, map[string][]byte{codec.DefaultKeyID: oldKey})
+	before, err := codec.NewDataConverterBuilder().WithKeyring(keyring).Build()
+	require.NoError(t, err)
+	after, err := codec.NewDataConverterBuilder().WithKeyring(keyring).WithKeyProvider(codec.NewKeyringKeyProvider(keyring)).Build()
+	require.NoError(t, err)
+
+	order := largeOrder(10)
+
+	// Payloads written before envelope encryption still decode
+	legacy, err := before.ToPayload(order)
+	require.NoError(t, err)
+	assert.Equal(t, codec.MetadataEncodingEncrypted, string(legacy.Metadata["encoding"]))
+	var got models.Order
+	require.NoError(t, after.FromPayload(legacy, &got))
+	assert.Equal(t, order, got)
+
+	// New payloads use envelope encryption only
+	payload, err := after.ToPayload(order)
+	require.NoError(t, err)
+	assert.Equal(t, codec.MetadataEncodingEnvelope, string(payload.Metadata["encoding"]))
+	got = models.Order{}
+	require.NoError(t, after.FromPayload(payload, &got))
+	assert.Equal(t, order, got)
+}
+
+func TestLoadEncryptionConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		wantKeyring   bool
+		wantGenerated bool
+		wantKMS       bool
+		wantErr       error
+	}{
+		{
+			name:    "Failure - No Key Without Dev Mode",
+			env:     map[string]string{},
+			wantErr: codec.ErrNoEncryptionKey,
+		},
+		{
+			name:          "Success - Dev Mode Generates Key",
+			env:           map[string]string{"ENCRYPTION_DEV_MODE": "true"},
+			wantKeyring:   true,
+			wantGenerated: true,
+		},
+		{
+			name:        "Success - Configured Key",
+			env:         map[string]string{"ENCRYPTION_KEY": hex.EncodeToString(newKey), "ENCRYPTION_DEV_MODE": "true"},
+			wantKeyring: true,
+		},
+		{
+			name:        "Success - Field Level Encryption",
+			env:         map[string]string{"ENCRYPTION_KEY": hex.EncodeToString(newKey), "ENCRYPTION_MODE": "fields"},
+			wantKeyring: true,
+		},
+		{
+			name:    "Success - KMS Without Local Keys",
+			env:     map[string]string{"ENCRYPTION_KMS_ENDPOINT": "http://localhost:8095"},
+			wantKMS: true,
+		},
+		{
+			name:        "Success - KMS With Keys For Older Payloads",
+			env:         map[string]string{"ENCRYPTION_KMS_ENDPOINT": "http://localhost:8095", "ENCRYPTION_KEY": hex.EncodeToString(newKey)},
+			wantKeyring: true,
+			wantKMS:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ENCRYPTION_KEYRING_FILE", "ENCRYPTION_KEY", "ENCRYPTION_KEY_ID", "ENCRYPTION_OLD_KEYS", "ENCRYPTION_KMS_ENDPOINT", "ENCRYPTION_DEV_MODE", "ENCRYPTION_MODE", "CLAIM_CHECK_DIR", "CLAIM_CHECK_S3_ENDPOINT"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			config, err := codec.LoadEncryptionConfig()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Contains(t, err.Error(), "ENCRYPTION_DEV_MODE")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKeyring, config.Keyring != nil)
+			assert.Equal(t, tt.wantGenerated, config.GeneratedKey != nil)
+			assert.Equal(t, tt.env["ENCRYPTION_MODE"] == "fields", config.FieldLevel)
+			_, isKMS := config.KeyProvider.(*codec.HTTPKeyProvider)
+			assert.Equal(t, tt.wantKMS, isKMS)
+
+			_, err = config.Builder().Build()
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"temporal-order-system/codec"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+)
+
+// customerOrder builds an order carrying every PII field
+func customerOrder() models.Order {
+	order := largeOrder(2)
+	order.ID = "ORD-PII-001"
+	order.CustomerName = "Ada Lovelace"
+	order.CustomerEmail = "ada@example.com"
+	order.CardToken = "tok_4242424242424242"
+	order.ShippingAddress.Line2 = "Suite 300"
+	order.ShippingAddress.State = "CA"
+	return order
+}
+
+func TestFieldEncryptionConverter(t *testing.T) {
+	keyring := mustKeyring(t, "master-1", map[string][]byte{"master-1": newKey})
+	fields := codec.NewFieldEncryptionConverter(codec.NewKeyringKeyProvider(keyring))
+	secrets := []string{"Ada Lovelace", "ada@example.com", "tok_4242424242424242", "1 Market Street", "Suite 300", "San Francisco", "94105"}
+
+	tests := []struct {
+		name        string
+		value       interface{}
+		decode      func(t *testing.T, decode func(valuePtr interface{}))
+		wantFields  bool
+		wantVisible []string
+	}{
+		{
+			name:        "Success - Order",
+			value:       customerOrder(),
+			wantFields:  true,
+			wantVisible: []string{`"id":"ORD-PII-001"`, `"status":"PENDING"`, `"minor_units":`, `"country":"US"`, `"state":"CA"`, "Sample Product 1"},
+			decode: func(t *testing.T, decode func(valuePtr interface{})) {
+				var got models.Order
+				decode(&got)
+				assert.Equal(t, customerOrder(), got)
+			},
+		},
+		{
+			name:        "Success - Nested Orders",
+			value:       map[string][]*models.Order{"open": {ptr(customerOrder())}},
+			wantFields:  true,
+			wantVisible: []string{`"id":"ORD-PII-001"`},
+			decode: func(t *testing.T, decode func(valuePtr interface{})) {
+				var got map[string][]*models.Order
+				decode(&got)
+				assert.Equal(t, customerOrder(), *got["open"][0])
+			},
+		},
+		{
+			name:        "Success - Address Update",
+			value:       *customerOrder().ShippingAddress,
+			wantFields:  true,
+			wantVisible: []string{`"country":"US"`},
+			decode: func(t *testing.T, decode func(valuePtr interface{})) {
+				var got models.Address
+				decode(&got)
+				assert.Equal(t, *customerOrder().ShippingAddress, got)
+			},
+		},
+		{
+			name:        "Success - Order Without PII",
+			value:       models.Order{ID: "ORD-PII-002", Status: models.OrderStatusPending},
+			wantVisible: []string{`"id":"ORD-PII-002"`},
+			decode: func(t *testing.T, decode func(valuePtr interface{})) {
+				var got models.Order
+				decode(&got)
+				assert.Equal(t, "ORD-PII-002", got.ID)
+			},
+		},
+		{
+			name:        "Success - Type Without PII Fields",
+			value:       models.WorkflowState{OrderID: "ORD-PII-003"},
+			wantVisible: []string{`"order_id":"ORD-PII-003"`},
+			decode: func(t *testing.T, decode func(valuePtr interface{})) {
+				var got models.WorkflowState
+				decode(&got)
+				assert.Equal(t, "ORD-PII-003", got.OrderID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := fields.ToPayload(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, "json/plain", string(payload.Metadata["encoding"]))
+
+			_, hasFields := payload.Metadata[codec.MetadataEncryptedFields]
+			assert.Equal(t, tt.wantFields, hasFields)
+			if tt.wantFields {
+				assert.Equal(t, "master-1", string(payload.Metadata[codec.MetadataEncryptionKeyID]))
+				assert.Contains(t, string(payload.Data), `"$encrypted"`)
+			}
+			for _, visible := range tt.wantVisible {
+				assert.Contains(t, string(payload.Data), visible)
+			}
+			for _, secret := range secrets {
+				assert.NotContains(t, string(payload.Data), secret)
+			}
+
+			tt.decode(t, func(valuePtr interface{}) {
+				require.NoError(t, fields.FromPayload(payload, valuePtr))
+			})
+		})
+	}
+}
+
+func TestFieldEncryptionConverter_WrongKey(t *testing.T) {
+	fields := codec.NewFieldEncryptionConverter(codec.NewKeyringKeyProvider(mustKeyring(t, "master-1", map[string][]byte{"master-1": newKey})))
+	payload, err := fields.ToPayload(customerOrder())
+	require.NoError(t, err)
+
+	other := codec.NewFieldEncryptionConverter(codec.NewKeyringKeyProvider(mustKeyring(t, "master-1", map[string][]byte{"master-1": oldKey})))
+	var got models.Order
+	err = other.FromPayload(payload, &got)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt fields")
+}
+
+// countingKeyProvider counts the calls to the key provider it wraps
+type countingKeyProvider struct {
+	codec.KeyProvider
+	generated atomic.Int32
+	decrypted atomic.Int32
+}
+
+func (p *countingKeyProvider) GenerateDataKey(ctx context.Context) (codec.DataKey, error) {
+	p.generated.Add(1)
+	return p.KeyProvider.GenerateDataKey(ctx)
+}
+
+func (p *countingKeyProvider) DecryptDataKey(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	p.decrypted.Add(1)
+	return p.KeyProvider.DecryptDataKey(ctx, masterKeyID, wrapped)
+}
+
+func TestFieldEncryptionConverter_DataKeyCache(t *testing.T) {
+	keyring := mustKeyring(t, "master-1", map[string][]byte{"master-1": newKey})
+
+	t.Run("Success - Data Key Reused Between Conversions", func(t *testing.T) {
+		provider := &countingKeyProvider{KeyProvider: codec.NewKeyringKeyProvider(keyring)}
+		fields := codec.NewFieldEncryptionConverter(provider)
+
+		first, err := fields.ToPayload(customerOrder())
+		require.NoError(t, err)
+		second, err := fields.ToPayload(customerOrder())
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), provider.generated.Load())
+		assert.Equal(t, first.Metadata[codec.MetadataEncryptionDataKey], second.Metadata[codec.MetadataEncryptionDataKey])
+
+		// Another converter of the same keyring unwraps the key once
+		reader := &countingKeyProvider{KeyProvider: codec.NewKeyringKeyProvider(keyring)}
+		other := codec.NewFieldEncryptionConverter(reader)
+		for _, payload := range []*commonpb.Payload{first, second} {
+			var got models.Order
+			require.NoError(t, other.FromPayload(payload, &got))
+			assert.Equal(t, customerOrder(), got)
+		}
+		assert.Equal(t, int32(1), reader.decrypted.Load())
+	})
+
+	t.Run("Success - Data Key Rotated In The Background", func(t *testing.T) {
+		provider := &countingKeyProvider{KeyProvider: codec.NewKeyringKeyProvider(keyring)}
+		fields := codec.NewFieldEncryptionConverterWithRotation(provider, 50*time.Millisecond)
+
+		first, err := fields.ToPayload(customerOrder())
+		require.NoError(t, err)
+		time.Sleep(60 * time.Millisecond)
+
+		// The expired key is still used while its replacement is generated
+		_, err = fields.ToPayload(customerOrder())
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			return provider.generated.Load() == 2
+		}, time.Second, 5*time.Millisecond)
+
+		rotated, err := fields.ToPayload(customerOrder())
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Metadata[codec.MetadataEncryptionDataKey], rotated.Metadata[codec.MetadataEncryptionDataKey])
+
+		var got models.Order
+		require.NoError(t, fields.FromPayload(first, &got))
+		assert.Equal(t, customerOrder(), got)
+	})
+}
+
+func TestDataConverterBuilder_FieldEncryption(t *testing.T) {
+	keyring := mustKeyring(t, codec.DefaultKeyID, map[string][]byte{codec.DefaultKeyID: newKey})
+	whole, err := codec.NewDataConverterBuilder().WithKeyring(keyring).WithKeyProvider(codec.NewKeyringKeyProvider(keyring)).Build()
+	require.NoError(t, err)
+	fields, err := codec.NewDataConverterBuilder().WithKeyring(keyring).WithKeyProvider(codec.NewKeyringKeyProvider(keyring)).WithoutCompression().WithFieldEncryption().Build()
+	require.NoError(t, err)
+
+	order := customerOrder()
+
+	// Only the PII fields are encrypted
+	payload, err := fields.ToPayload(order)
+	require.NoError(t, err)
+	assert.Equal(t, "json/plain", string(payload.Metadata["encoding"]))
+	assert.Contains(t, string(payload.Data), `"id":"ORD-PII-001"`)
+	assert.NotContains(t, string(payload.Data), "ada@example.com")
+	var got models.Order
+	require.NoError(t, fields.FromPayload(payload, &got))
+	assert.Equal(t, order, got)
+
+	// Payloads encrypted whole before switching still decode
+	legacy, err := whole.ToPayload(order)
+	require.NoError(t, err)
+	assert.Equal(t, codec.MetadataEncodingEnvelope, string(legacy.Metadata["encoding"]))
+	got = models.Order{}
+	require.NoError(t, fields.FromPayload(legacy, &got))
+	assert.Equal(t, order, got)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
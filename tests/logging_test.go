@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/gateway"
+	"temporal-order-system/logging"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	sdklog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// logLines returns the JSON log lines of buf with the given message
+func logLines(t *testing.T, buf *bytes.Buffer, msg string) []string {
+	t.Helper()
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+		if entry["msg"] == msg {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return lines
+}
+
+// assertCorrelated asserts a JSON log line carries every correlation field exactly once
+func assertCorrelated(t *testing.T, line, orderID string) {
+	t.Helper()
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &entry))
+	assert.Equal(t, orderID, entry[logging.FieldOrderID], line)
+	for _, field := range []string{logging.FieldOrderID, logging.FieldWorkflowID, logging.FieldRunID, logging.FieldAttempt, logging.FieldTaskQueue} {
+		assert.Contains(t, entry, field, line)
+		assert.Equal(t, 1, strings.Count(line, `"`+field+`"`), line)
+	}
+	assert.NotContains(t, line, `"WorkflowID"`)
+}
+
+func TestLoggingInterceptor_OrderWorkflow(t *testing.T) {
+	order := testOrder("TEST-LOG-001")
+
+	var buf bytes.Buffer
+	testSuite := &testsuite.WorkflowTestSuite{}
+	testSuite.SetLogger(sdklog.NewStructuredLogger(logging.NewSlogLogger(&buf, logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON})))
+	env := newOrderTestEnv(testSuite)
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{logging.NewInterceptor()},
+	})
+
+	env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(time.Minute).Return(nil)
+	env.mockSuccess()
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(workflows.SignalExpedite, nil)
+	}, time.Second)
+
+	env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	for _, msg := range []string{
+		"OrderWorkflow started",
+		"Order expedited via signal",
+		"OrderWorkflow completed successfully",
+		"PaymentWorkflow started",
+	} {
+		lines := logLines(t, &buf, msg)
+		require.NotEmpty(t, lines, msg)
+		for _, line := range lines {
+			assertCorrelated(t, line, order.ID)
+		}
+	}
+}
+
+func TestLoggingInterceptor_Activity(t *testing.T) {
+	order := models.Order{
+		ID:     "TEST-LOG-002",
+		Amount: models.NewMoney(50000, "USD"),
+	}
+
+	validationServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.ValidationResponse{Valid: true, Message: "Order is valid"})
+	}))
+	defer validationServer.Close()
+
+	var buf bytes.Buffer
+	testSuite := &testsuite.WorkflowTestSuite{}
+	testSuite.SetLogger(sdklog.NewStructuredLogger(logging.NewSlogLogger(&buf, logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON})))
+	env := testSuite.NewTestActivityEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{logging.NewInterceptor()},
+	})
+
+	act := activities.NewActivities(validationServer.URL)
+	env.RegisterActivity(act.ValidateOrder)
+
+	_, err := env.ExecuteActivity(act.ValidateOrder, order)
+	require.NoError(t, err)
+
+	for _, msg := range []string{"Validating order", "Order validated successfully"} {
+		lines := logLines(t, &buf, msg)
+		require.Len(t, lines, 1, msg)
+		assertCorrelated(t, lines[0], order.ID)
+	}
+}
+
+func TestLoggingInterceptor_OrderIDFromWorkflowID(t *testing.T) {
+	order := models.Order{
+		ID:     "TEST-LOG-003",
+		Amount: models.NewMoney(50000, "USD"),
+	}
+	paymentAct := activities.NewPaymentActivities(gateway.NewFakeGateway())
+
+	// Void and refund take no order, like the compensations of the payment workflow
+	compensate := func(ctx workflow.Context) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+		var authorizationID, transactionID string
+		if err := workflow.ExecuteActivity(ctx, paymentAct.AuthorizePayment, order).Get(ctx, &authorizationID); err != nil {
+			return err
+		}
+		if err := workflow.ExecuteActivity(ctx, paymentAct.CapturePayment, order, authorizationID).Get(ctx, &transactionID); err != nil {
+			return err
+		}
+		if err := workflow.ExecuteActivity(ctx, paymentAct.RefundPayment, transactionID, order.Amount).Get(ctx, nil); err != nil {
+			return err
+		}
+		if err := workflow.ExecuteActivity(ctx, paymentAct.AuthorizePayment, order).Get(ctx, &authorizationID); err != nil {
+			return err
+		}
+		return workflow.ExecuteActivity(ctx, paymentAct.VoidAuthorization, authorizationID).Get(ctx, nil)
+	}
+
+	var buf bytes.Buffer
+	testSuite := &testsuite.WorkflowTestSuite{}
+	testSuite.SetLogger(sdklog.NewStructuredLogger(logging.NewSlogLogger(&buf, logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON})))
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{logging.NewInterceptor()},
+	})
+	env.SetStartWorkflowOptions(client.StartWorkflowOptions{ID: workflows.PaymentWorkflowID(order.ID)})
+	env.RegisterWorkflowWithOptions(compensate, workflow.RegisterOptions{Name: "Compensate"})
+	env.RegisterActivity(paymentAct)
+
+	env.ExecuteWorkflow("Compensate")
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	for _, msg := range []string{"Refunding payment", "Refund processed successfully", "Voiding authorization", "Authorization voided successfully"} {
+		lines := logLines(t, &buf, msg)
+		require.Len(t, lines, 1, msg)
+		assertCorrelated(t, lines[0], order.ID)
+	}
+}
+
+func TestOrderIDFromWorkflowID(t *testing.T) {
+	tests := []struct {
+		name       string
+		workflowID string
+		want       string
+	}{
+		{name: "Success - Order Workflow", workflowID: workflows.OrderWorkflowID("ORD-1"), want: "ORD-1"},
+		{name: "Success - Payment Workflow", workflowID: workflows.PaymentWorkflowID("ORD-1"), want: "ORD-1"},
+		{name: "Failure - Other Workflow", workflowID: "reports-2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, workflows.OrderIDFromWorkflowID(tt.workflowID))
+		})
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	defaults := logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON}
+
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		want    logging.Options
+		wantErr string
+	}{
+		{
+			name: "Success - Defaults",
+			want: defaults,
+		},
+		{
+			name:   "Success - Level And Format",
+			level:  "debug",
+			format: "text",
+			want:   logging.Options{Level: slog.LevelDebug, Format: logging.FormatText},
+		},
+		{
+			name:  "Success - Upper Case Level",
+			level: "WARN",
+			want:  logging.Options{Level: slog.LevelWarn, Format: logging.FormatJSON},
+		},
+		{
+			name:    "Failure - Unknown Level",
+			level:   "verbose",
+			wantErr: "invalid LOG_LEVEL",
+		},
+		{
+			name:    "Failure - Unknown Format",
+			format:  "xml",
+			wantErr: "invalid LOG_FORMAT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tt.level)
+			t.Setenv("LOG_FORMAT", tt.format)
+
+			opts, err := logging.OptionsFromEnv(defaults)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+		})
+	}
+}
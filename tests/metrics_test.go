@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"temporal-order-system/metrics"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// scrape closes a registry, reporting its pending metrics, and returns its Prometheus exposition
+func scrape(t *testing.T, registry *metrics.Registry) string {
+	t.Helper()
+	require.NoError(t, registry.Close())
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	return rec.Body.String()
+}
+
+// sample returns the value of the first sample of a metric whose labels contain every label,
+// or "" when there is none
+func sample(exposition, name string, labels ...string) string {
+	scanner := bufio.NewScanner(strings.NewReader(exposition))
+	for scanner.Scan() {
+		line := scanner.Text()
+		series, value, ok := strings.Cut(line, " ")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if series != name && !strings.HasPrefix(series, name+"{") {
+			continue
+		}
+		matches := true
+		for _, label := range labels {
+			if !strings.Contains(series, label) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestRegistry_Exposition(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(h client.MetricsHandler)
+		want   []string
+	}{
+		{
+			name: "Success - Counter With Tags",
+			record: func(h client.MetricsHandler) {
+				counter := h.WithTags(map[string]string{"reason": "payment"}).Counter("orders_failed")
+				counter.Inc(1)
+				counter.Inc(2)
+				h.Counter("orders_started").Inc(1)
+			},
+			want: []string{
+				"# HELP orders_failed_total Orders that failed, by reason.",
+				"# TYPE orders_failed_total counter",
+				`orders_failed_total{reason="payment"} 3`,
+				"# HELP orders_started_total Order workflows that started.",
+				"orders_started_total 1",
+			},
+		},
+		{
+			name: "Success - Tags Merged",
+			record: func(h client.MetricsHandler) {
+				h.WithTags(map[string]string{"task_queue": "q", "outcome": "a"}).
+					WithTags(map[string]string{"outcome": "b"}).
+					Gauge("workers").Update(2.5)
+			},
+			want: []string{
+				"# HELP workers workers gauge",
+				"# TYPE workers gauge",
+				`workers{outcome="b",task_queue="q"} 2.5`,
+			},
+		},
+		{
+			name: "Success - Timer As Histogram",
+			record: func(h client.MetricsHandler) {
+				timer := h.Timer("payment_authorize_latency")
+				timer.Record(20 * time.Millisecond)
+				timer.Record(2 * time.Second)
+				timer.Record(2 * time.Minute)
+			},
+			want: []string{
+				"# HELP payment_authorize_latency_seconds Latency of payment gateway authorizations, by outcome.",
+				"# TYPE payment_authorize_latency_seconds histogram",
+				`payment_authorize_latency_seconds_bucket{le="0.01"} 0`,
+				`payment_authorize_latency_seconds_bucket{le="0.025"} 1`,
+				`payment_authorize_latency_seconds_bucket{le="2.5"} 2`,
+				`payment_authorize_latency_seconds_bucket{le="60"} 2`,
+				`payment_authorize_latency_seconds_bucket{le="+Inf"} 3`,
+				"payment_authorize_latency_seconds_sum 122.02",
+				"payment_authorize_latency_seconds_count 3",
+			},
+		},
+		{
+			name: "Success - Names And Labels Sanitized",
+			record: func(h client.MetricsHandler) {
+				h.WithTags(map[string]string{"error": `say "no"`}).Counter("temporal.request-failure").Inc(1)
+			},
+			want: []string{
+				"# HELP temporal_request_failure_total temporal_request_failure_total counter",
+				`temporal_request_failure_total{error="say__no_"} 1`,
+			},
+		},
+		{
+			name: "Failure - Other Tags Dropped",
+			record: func(h client.MetricsHandler) {
+				h.Counter("orders_started").Inc(1)
+				h.WithTags(map[string]string{"reason": "x"}).Counter("orders_started").Inc(7)
+			},
+			want: []string{
+				"# TYPE orders_started_total counter",
+				"orders_started_total 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			tt.record(registry.Handler())
+
+			exposition := scrape(t, registry)
+			for _, line := range tt.want {
+				assert.Contains(t, exposition, line+"\n")
+			}
+		})
+	}
+}
+
+func TestOrderWorkflow_Metrics(t *testing.T) {
+	order := testOrder("TEST-METRICS-001")
+
+	tests := []struct {
+		name         string
+		validateErr  error
+		authorizeErr error
+		want         map[string][]string
+		wantAbsent   []string
+	}{
+		{
+			name: "Success - Completed",
+			want: map[string][]string{
+				metrics.OrdersStarted:   nil,
+				metrics.OrdersCompleted: nil,
+			},
+			wantAbsent: []string{metrics.OrdersFailed, metrics.CompensationRuns},
+		},
+		{
+			name:        "Failure - Validation",
+			validateErr: temporal.NewNonRetryableApplicationError("order amount exceeds limit", "ValidationFailed", nil),
+			want: map[string][]string{
+				metrics.OrdersStarted: nil,
+				metrics.OrdersFailed:  {`reason="validation"`},
+			},
+			wantAbsent: []string{metrics.OrdersCompleted, metrics.CompensationRuns},
+		},
+		{
+			name:         "Failure - Payment Compensated",
+			authorizeErr: temporal.NewNonRetryableApplicationError("card declined", "Declined", nil),
+			want: map[string][]string{
+				metrics.OrdersStarted:    nil,
+				metrics.OrdersFailed:     {`reason="payment"`},
+				metrics.CompensationRuns: {`outcome="completed"`},
+			},
+			wantAbsent: []string{metrics.OrdersCompleted, metrics.CompensationFailures},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			testSuite := &testsuite.WorkflowTestSuite{}
+			testSuite.SetMetricsHandler(registry.Handler())
+			env := newOrderTestEnv(testSuite)
+
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).Return(tt.validateErr)
+			env.OnActivity(env.paymentAct.AuthorizePayment, mock.Anything, mock.Anything).Return("AUTH-1", tt.authorizeErr)
+			env.mockSuccess()
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+			require.True(t, env.IsWorkflowCompleted())
+
+			exposition := scrape(t, registry)
+			// Every business metric of the order workflow is a counter
+			for name, labels := range tt.want {
+				assert.Equal(t, "1", sample(exposition, name+"_total", labels...), name)
+			}
+			for _, name := range tt.wantAbsent {
+				assert.Empty(t, sample(exposition, name+"_total"), name)
+			}
+		})
+	}
+}
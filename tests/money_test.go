@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		currency      string
+		want          models.Money
+		wantDecimal   string
+		wantErr       bool
+		errorContains string
+	}{
+		{
+			name:        "Success - Two Decimals",
+			value:       "333.33",
+			currency:    "USD",
+			want:        models.NewMoney(33333, "USD"),
+			wantDecimal: "333.33",
+		},
+		{
+			name:        "Success - Whole Number",
+			value:       "1000",
+			currency:    "EUR",
+			want:        models.NewMoney(100000, "EUR"),
+			wantDecimal: "1000.00",
+		},
+		{
+			name:        "Success - Single Decimal",
+			value:       "0.5",
+			currency:    "USD",
+			want:        models.NewMoney(50, "USD"),
+			wantDecimal: "0.50",
+		},
+		{
+			name:        "Success - Negative",
+			value:       "-12.34",
+			currency:    "USD",
+			want:        models.NewMoney(-1234, "USD"),
+			wantDecimal: "-12.34",
+		},
+		{
+			name:        "Success - Zero Decimal Currency",
+			value:       "1500",
+			currency:    "JPY",
+			want:        models.NewMoney(1500, "JPY"),
+			wantDecimal: "1500",
+		},
+		{
+			name:        "Success - Three Decimal Currency",
+			value:       "1.234",
+			currency:    "KWD",
+			want:        models.NewMoney(1234, "KWD"),
+			wantDecimal: "1.234",
+		},
+		{
+			name:          "Failure - Too Many Decimals",
+			value:         "333.333",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: "invalid USD amount",
+		},
+		{
+			name:          "Failure - Invalid Currency",
+			value:         "10.00",
+			currency:      "usd",
+			wantErr:       true,
+			errorContains: "invalid ISO-4217 currency code",
+		},
+		{
+			name:          "Failure - Not A Number",
+			value:         "ten",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: "invalid USD amount",
+		},
+		{
+			name:          "Failure - Double Sign",
+			value:         "--5",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: `invalid USD amount "--5"`,
+		},
+		{
+			name:          "Failure - Plus Sign",
+			value:         "+5",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: "invalid USD amount",
+		},
+		{
+			name:          "Failure - Signed Fraction",
+			value:         "1.+5",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: "invalid USD amount",
+		},
+		{
+			name:          "Failure - Whole Part Overflows",
+			value:         "99999999999999999999",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: "amount out of range",
+		},
+		{
+			name:          "Failure - Minor Units Overflow",
+			value:         "9223372036854775807",
+			currency:      "USD",
+			wantErr:       true,
+			errorContains: "amount out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := models.ParseMoney(tt.value, tt.currency)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDecimal, got.Decimal())
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    models.Money
+		wantErr bool
+	}{
+		{
+			name: "Success - Object",
+			json: `{"minor_units": 33333, "currency": "EUR"}`,
+			want: models.NewMoney(33333, "EUR"),
+		},
+		{
+			name: "Success - Legacy Number",
+			json: `1000.5`,
+			want: models.NewMoney(100050, "USD"),
+		},
+		{
+			name: "Success - Legacy Float Drift Is Rounded",
+			json: `333.33333333333337`,
+			want: models.NewMoney(33333, "USD"),
+		},
+		{
+			name: "Success - Object Without Currency",
+			json: `{"minor_units": 500}`,
+			want: models.NewMoney(500, "USD"),
+		},
+		{
+			name:    "Failure - Invalid Currency",
+			json:    `{"minor_units": 500, "currency": "dollars"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure - Unknown Field",
+			json:    `{"minor_units": 100, "curency": "USD"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure - String",
+			json:    `"ten"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.Money
+			err := json.Unmarshal([]byte(tt.json), &got)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// Round trip through the canonical encoding
+			data, err := json.Marshal(got)
+			require.NoError(t, err)
+			var again models.Money
+			require.NoError(t, json.Unmarshal(data, &again))
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
+func TestLegacyOrderPayload(t *testing.T) {
+	legacy := `{"id":"ORDER-1","amount":1000,"items":[` +
+		`{"product_id":"PROD-001","name":"Sample Product 1","quantity":2,"price":333.3333333333333},` +
+		`{"product_id":"PROD-002","name":"Sample Product 2","quantity":1,"price":333.3333333333333}]}`
+
+	var order models.Order
+	require.NoError(t, json.Unmarshal([]byte(legacy), &order))
+	assert.Equal(t, models.NewMoney(100000, "USD"), order.Amount)
+	assert.Equal(t, models.NewMoney(33333, "USD"), order.Items[0].Price)
+
+	total, err := order.Total()
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(99999, "USD"), total)
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := models.NewMoney(1050, "USD")
+
+	sum, err := usd.Add(models.NewMoney(250, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(1300, "USD"), sum)
+
+	sum, err = models.Money{}.Add(usd)
+	require.NoError(t, err)
+	assert.Equal(t, usd, sum)
+
+	_, err = usd.Add(models.NewMoney(100, "EUR"))
+	assert.ErrorContains(t, err, "currency mismatch")
+
+	cmp, err := usd.Cmp(models.NewMoneyFromMajor(10, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+
+	product, err := usd.Mul(3)
+	require.NoError(t, err)
+	assert.Equal(t, models.NewMoney(3150, "USD"), product)
+	assert.Equal(t, "10.50 USD", usd.String())
+
+	largest := models.NewMoney(math.MaxInt64, "USD")
+	_, err = largest.Mul(2)
+	assert.ErrorIs(t, err, models.ErrOverflow)
+	_, err = models.NewMoney(math.MinInt64, "USD").Mul(-1)
+	assert.ErrorIs(t, err, models.ErrOverflow)
+	_, err = largest.Add(models.NewMoney(1, "USD"))
+	assert.ErrorIs(t, err, models.ErrOverflow)
+	_, err = models.NewMoney(math.MinInt64, "USD").Sub(models.NewMoney(1, "USD"))
+	assert.ErrorIs(t, err, models.ErrOverflow)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestOrderWorkflow_WaitForChange(t *testing.T) {
+	order := testOrder("TEST-PROG-001")
+
+	tests := []struct {
+		name            string
+		waitAt          time.Duration
+		seenVersion     bool
+		timeout         time.Duration
+		validationDelay time.Duration
+		notifyDelay     time.Duration
+		wantRejected    string
+		wantAfter       time.Duration
+		wantStatus      models.OrderStatus
+		wantStep        string
+		wantFinal       bool
+	}{
+		{
+			name:            "Success - Current Progress At Once",
+			waitAt:          time.Second,
+			validationDelay: 10 * time.Second,
+			wantAfter:       time.Second,
+			wantStatus:      models.OrderStatusPending,
+			wantStep:        "ValidateOrder",
+		},
+		{
+			name:            "Success - Returns On Next Change",
+			waitAt:          time.Second,
+			seenVersion:     true,
+			validationDelay: 10 * time.Second,
+			wantAfter:       10 * time.Second,
+			wantStatus:      models.OrderStatusValidated,
+			wantStep:        "PaymentWorkflow",
+		},
+		{
+			name:            "Success - Times Out Unchanged",
+			waitAt:          time.Second,
+			seenVersion:     true,
+			timeout:         3 * time.Second,
+			validationDelay: 10 * time.Second,
+			wantAfter:       4 * time.Second,
+			wantStatus:      models.OrderStatusPending,
+			wantStep:        "ValidateOrder",
+		},
+		{
+			name:        "Success - Final When Workflow Closes",
+			waitAt:      time.Second,
+			seenVersion: true,
+			notifyDelay: 10 * time.Second,
+			wantAfter:   10 * time.Second,
+			wantStatus:  models.OrderStatusCompleted,
+			wantStep:    "NotifyCustomer",
+			wantFinal:   true,
+		},
+		{
+			name:            "Rejected - Timeout Too Long",
+			waitAt:          time.Second,
+			timeout:         time.Hour,
+			validationDelay: 10 * time.Second,
+			wantRejected:    "timeout must be between",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(tt.validationDelay).Return(nil)
+			env.OnActivity(env.act.NotifyCustomer, mock.Anything, mock.Anything, mock.Anything).After(tt.notifyDelay).Return(nil)
+			env.mockSuccess()
+
+			start := env.Now()
+			var rejectErr, updateErr error
+			var progress models.OrderProgress
+			var completedAt time.Time
+			wait := func(version int64, timeout time.Duration, onComplete func(models.OrderProgress)) {
+				env.UpdateWorkflow(workflows.UpdateWaitForChange, "", &testsuite.TestUpdateCallback{
+					OnReject: func(err error) { rejectErr = err },
+					OnAccept: func() {},
+					OnComplete: func(result interface{}, err error) {
+						updateErr = err
+						if p, ok := result.(models.OrderProgress); ok {
+							onComplete(p)
+						}
+					},
+				}, workflows.ChangeWait{Version: version, Timeout: timeout})
+			}
+			done := func(p models.OrderProgress) {
+				progress = p
+				completedAt = env.Now()
+			}
+			env.RegisterDelayedCallback(func() {
+				if !tt.seenVersion {
+					wait(-1, tt.timeout, done)
+					return
+				}
+				// Read the current version at once, then wait for it to change
+				wait(-1, 0, func(current models.OrderProgress) {
+					wait(current.State.Version, tt.timeout, done)
+				})
+			}, tt.waitAt)
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			if tt.wantRejected != "" {
+				require.Error(t, rejectErr)
+				assert.Contains(t, rejectErr.Error(), tt.wantRejected)
+				return
+			}
+			require.NoError(t, rejectErr)
+			require.NoError(t, updateErr)
+
+			assert.Equal(t, tt.wantAfter, completedAt.Sub(start))
+			assert.Equal(t, tt.wantStatus, progress.State.Status)
+			assert.Equal(t, tt.wantFinal, progress.Final)
+			require.NotEmpty(t, progress.Timeline)
+			assert.Equal(t, tt.wantStep, progress.Timeline[len(progress.Timeline)-1].Step)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// signalAt is a signal sent at a point in workflow time
+type signalAt struct {
+	name string
+	at   time.Duration
+}
+
+func TestOrderWorkflow_Signals(t *testing.T) {
+	order := testOrder("TEST-SIG-001")
+
+	// Validation runs from 0s to 10s, payment from 10s to 20s and processing from 20s to 30s
+	const (
+		beforeValidation = 0
+		duringValidation = 5 * time.Second
+		duringPayment    = 15 * time.Second
+		duringProcessing = 25 * time.Second
+	)
+
+	tests := []struct {
+		name               string
+		signals            []signalAt
+		wantStatus         models.OrderStatus
+		wantExpedited      bool
+		wantValidated      bool
+		wantPaid           bool
+		wantCompensations  []string
+		wantIgnored        int
+		wantValidationBeat time.Duration
+		wantProcessingBeat time.Duration
+		wantNotification   string
+	}{
+		{
+			name:              "Cancel Before Validation - Nothing To Undo",
+			signals:           []signalAt{{workflows.SignalCancel, beforeValidation}},
+			wantStatus:        models.OrderStatusCancelled,
+			wantCompensations: nil,
+		},
+		{
+			name:              "Cancel During Validation - Rolled Back Without Payment",
+			signals:           []signalAt{{workflows.SignalCancel, duringValidation}},
+			wantStatus:        models.OrderStatusCancelled,
+			wantValidated:     true,
+			wantCompensations: []string{"RollbackOrder"},
+		},
+		{
+			name:              "Cancel During Payment - Refunded And Rolled Back",
+			signals:           []signalAt{{workflows.SignalCancel, duringPayment}},
+			wantStatus:        models.OrderStatusCancelled,
+			wantValidated:     true,
+			wantPaid:          true,
+			wantCompensations: []string{"RefundPayment", "RollbackOrder"},
+		},
+		{
+			name:               "Cancel During Processing - Ignored",
+			signals:            []signalAt{{workflows.SignalCancel, duringProcessing}},
+			wantStatus:         models.OrderStatusCompleted,
+			wantValidated:      true,
+			wantPaid:           true,
+			wantIgnored:        1,
+			wantValidationBeat: 5 * time.Second,
+			wantProcessingBeat: 5 * time.Second,
+			wantNotification:   "Your order has been processed successfully",
+		},
+		{
+			name:               "Expedite Before Validation - Validation And Processing Expedited",
+			signals:            []signalAt{{workflows.SignalExpedite, beforeValidation}},
+			wantStatus:         models.OrderStatusCompleted,
+			wantExpedited:      true,
+			wantValidated:      true,
+			wantPaid:           true,
+			wantValidationBeat: 3 * time.Second,
+			wantProcessingBeat: 3 * time.Second,
+			wantNotification:   "Your expedited order has been processed successfully",
+		},
+		{
+			name:               "Expedite During Payment - Processing Expedited",
+			signals:            []signalAt{{workflows.SignalExpedite, duringPayment}},
+			wantStatus:         models.OrderStatusCompleted,
+			wantExpedited:      true,
+			wantValidated:      true,
+			wantPaid:           true,
+			wantValidationBeat: 5 * time.Second,
+			wantProcessingBeat: 3 * time.Second,
+			wantNotification:   "Your expedited order has been processed successfully",
+		},
+		{
+			name:               "Expedite During Processing - Notification Only",
+			signals:            []signalAt{{workflows.SignalExpedite, duringProcessing}},
+			wantStatus:         models.OrderStatusCompleted,
+			wantExpedited:      true,
+			wantValidated:      true,
+			wantPaid:           true,
+			wantValidationBeat: 5 * time.Second,
+			wantProcessingBeat: 5 * time.Second,
+			wantNotification:   "Your expedited order has been processed successfully",
+		},
+		{
+			name: "Duplicate Cancel - Compensates Once",
+			signals: []signalAt{
+				{workflows.SignalCancel, duringValidation},
+				{workflows.SignalCancel, duringValidation + time.Second},
+			},
+			wantStatus:        models.OrderStatusCancelled,
+			wantValidated:     true,
+			wantCompensations: []string{"RollbackOrder"},
+			wantIgnored:       1,
+		},
+		{
+			name: "Cancel After Expedite - Still Cancels",
+			signals: []signalAt{
+				{workflows.SignalExpedite, duringValidation},
+				{workflows.SignalCancel, duringPayment},
+			},
+			wantStatus:        models.OrderStatusCancelled,
+			wantExpedited:     true,
+			wantValidated:     true,
+			wantPaid:          true,
+			wantCompensations: []string{"RefundPayment", "RollbackOrder"},
+		},
+		{
+			name: "Expedite After Cancel - Ignored",
+			signals: []signalAt{
+				{workflows.SignalCancel, duringValidation},
+				{workflows.SignalExpedite, duringValidation + time.Second},
+			},
+			wantStatus:        models.OrderStatusCancelled,
+			wantValidated:     true,
+			wantCompensations: []string{"RollbackOrder"},
+			wantIgnored:       1,
+		},
+		{
+			name: "Repeated Cancel - One Ignored Event",
+			signals: []signalAt{
+				{workflows.SignalCancel, duringValidation},
+				{workflows.SignalCancel, duringValidation + time.Second},
+				{workflows.SignalCancel, duringValidation + 2*time.Second},
+				{workflows.SignalCancel, duringValidation + 3*time.Second},
+			},
+			wantStatus:        models.OrderStatusCancelled,
+			wantValidated:     true,
+			wantCompensations: []string{"RollbackOrder"},
+			wantIgnored:       3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+
+			var validationBeat, processingBeat time.Duration
+			var notification string
+			var compensations []string
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(10 * time.Second).Return(
+				func(ctx context.Context, o models.Order) error {
+					validationBeat = activity.GetInfo(ctx).HeartbeatTimeout
+					return nil
+				})
+			env.OnActivity(env.paymentAct.AuthorizePayment, mock.Anything, mock.Anything).After(10*time.Second).Return("AUTH-1", nil)
+			env.OnActivity(env.act.ProcessOrder, mock.Anything, mock.Anything).After(10 * time.Second).Return(
+				func(ctx context.Context, o models.Order) error {
+					processingBeat = activity.GetInfo(ctx).HeartbeatTimeout
+					return nil
+				})
+			env.OnActivity(env.act.NotifyCustomer, mock.Anything, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, o models.Order, message string) error {
+					notification = message
+					return nil
+				})
+			env.OnActivity(env.act.RollbackOrder, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, o models.Order) error {
+					compensations = append(compensations, "RollbackOrder")
+					return nil
+				})
+			env.OnActivity(env.paymentAct.RefundPayment, mock.Anything, "TXN-1", order.Amount).Return(
+				func(ctx context.Context, transactionID string, amount models.Money) (string, error) {
+					compensations = append(compensations, "RefundPayment")
+					return "REFUND-1", nil
+				})
+			env.mockSuccess()
+
+			for _, s := range tt.signals {
+				env.RegisterDelayedCallback(func() {
+					env.SignalWorkflow(s.name, s.name)
+				}, s.at)
+			}
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			if tt.wantStatus == models.OrderStatusCancelled {
+				require.Error(t, env.GetWorkflowError())
+				assert.Contains(t, env.GetWorkflowError().Error(), "order cancelled by user")
+			} else {
+				require.NoError(t, env.GetWorkflowError())
+			}
+
+			val, err := env.QueryWorkflow(workflows.QueryState)
+			require.NoError(t, err)
+			var state models.WorkflowState
+			require.NoError(t, val.Get(&state))
+			assert.Equal(t, tt.wantStatus, state.Status)
+			assert.Equal(t, tt.wantExpedited, state.Expedited)
+			assert.Equal(t, tt.wantValidated, state.ValidationDone)
+			assert.Equal(t, tt.wantPaid, state.PaymentDone)
+			assert.Equal(t, tt.wantPaid && tt.wantStatus == models.OrderStatusCancelled, state.Refunded)
+			assert.Equal(t, tt.wantCompensations, compensations)
+
+			if tt.wantValidationBeat != 0 {
+				assert.Equal(t, tt.wantValidationBeat, validationBeat)
+			}
+			if tt.wantProcessingBeat != 0 {
+				assert.Equal(t, tt.wantProcessingBeat, processingBeat)
+			}
+			if tt.wantNotification != "" {
+				assert.Equal(t, tt.wantNotification, notification)
+			}
+
+			val, err = env.QueryWorkflow(workflows.QueryTimeline)
+			require.NoError(t, err)
+			var events []models.TimelineEvent
+			require.NoError(t, val.Get(&events))
+			// Ignored signals share one event per signal that counts them
+			signals, ignored := 0, 0
+			ignoredEvents := map[string]int{}
+			for _, e := range events {
+				if e.Trigger != "signal:"+e.Step {
+					continue
+				}
+				if e.Error == "" {
+					signals++
+					continue
+				}
+				signals += e.Count
+				ignored += e.Count
+				ignoredEvents[e.Step]++
+			}
+			assert.Equal(t, len(tt.signals), signals)
+			assert.Equal(t, tt.wantIgnored, ignored)
+			for step, n := range ignoredEvents {
+				assert.Equal(t, 1, n, step)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    models.OrderStatus
+		to      models.OrderStatus
+		wantErr bool
+	}{
+		{name: "Success - Pending To Validated", from: models.OrderStatusPending, to: models.OrderStatusValidated},
+		{name: "Success - Pending To Failed", from: models.OrderStatusPending, to: models.OrderStatusFailed},
+		{name: "Success - Validated To Cancelled", from: models.OrderStatusValidated, to: models.OrderStatusCancelled},
+		{name: "Success - Validated To Processing", from: models.OrderStatusValidated, to: models.OrderStatusProcessing},
+		{name: "Success - Processing To Completed", from: models.OrderStatusProcessing, to: models.OrderStatusCompleted},
+		{name: "Failure - Pending To Completed", from: models.OrderStatusPending, to: models.OrderStatusCompleted, wantErr: true},
+		{name: "Failure - Processing To Cancelled", from: models.OrderStatusProcessing, to: models.OrderStatusCancelled, wantErr: true},
+		{name: "Failure - Leaving Completed", from: models.OrderStatusCompleted, to: models.OrderStatusProcessing, wantErr: true},
+		{name: "Failure - Leaving Cancelled", from: models.OrderStatusCancelled, to: models.OrderStatusCompleted, wantErr: true},
+		{name: "Failure - Leaving Failed", from: models.OrderStatusFailed, to: models.OrderStatusValidated, wantErr: true},
+		{name: "Failure - Same Status", from: models.OrderStatusValidated, to: models.OrderStatusValidated, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := models.Transition(tt.from, tt.to)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "illegal order status transition")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	for _, status := range []models.OrderStatus{models.OrderStatusCompleted, models.OrderStatusCancelled, models.OrderStatusFailed} {
+		assert.True(t, status.IsTerminal(), status)
+	}
+	assert.False(t, models.OrderStatusProcessing.IsTerminal())
+}
+
+func TestWorkflowState_SetStatus(t *testing.T) {
+	state := models.WorkflowState{Status: models.OrderStatusCompleted}
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	err := state.SetStatus(models.OrderStatusFailed, at)
+	require.Error(t, err)
+	assert.Equal(t, models.OrderStatusCompleted, state.Status)
+	assert.True(t, state.LastUpdated.IsZero())
+
+	state.Status = models.OrderStatusProcessing
+	require.NoError(t, state.SetStatus(models.OrderStatusCompleted, at))
+	assert.Equal(t, models.OrderStatusCompleted, state.Status)
+	assert.Equal(t, at, state.LastUpdated)
+}
+
+func TestOrderWorkflow_ExpeditedOrderCompletes(t *testing.T) {
+	env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+
+	env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(5 * time.Second).Return(nil)
+	var notifications []string
+	env.OnActivity(env.act.NotifyCustomer, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, o models.Order, message string) error {
+			notifications = append(notifications, message)
+			return nil
+		})
+	env.mockSuccess()
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(workflows.SignalExpedite, "expedite")
+	}, time.Second)
+
+	order := testOrder("TEST-STATUS-001")
+	env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, []string{"Your expedited order has been processed successfully"}, notifications)
+
+	state := env.queryState(t)
+	assert.Equal(t, models.OrderStatusCompleted, state.Status)
+	assert.True(t, state.Expedited)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestOrderWorkflow_Timeline(t *testing.T) {
+	order := testOrder("TEST-TL-001")
+
+	// wantEvent describes the expected step, trigger, attempts and whether it failed
+	type wantEvent struct {
+		step     string
+		trigger  string
+		attempts int
+		failed   bool
+	}
+
+	tests := []struct {
+		name        string
+		validateErr error
+		signal      string
+		signalAt    time.Duration
+		want        []wantEvent
+	}{
+		{
+			name: "Success - Every Step Recorded",
+			want: []wantEvent{
+				{step: "ValidateOrder"},
+				{step: "PaymentWorkflow"},
+				{step: "ProcessOrder"},
+				{step: "NotifyCustomer"},
+			},
+		},
+		{
+			name:     "Cancel Signal - Triggers Compensation",
+			signal:   workflows.SignalCancel,
+			signalAt: time.Second,
+			want: []wantEvent{
+				{step: "ValidateOrder"},
+				{step: "cancel", trigger: "signal:cancel"},
+				{step: "Compensate", trigger: "signal:cancel"},
+			},
+		},
+		{
+			name:        "Validation Failure - Error Recorded",
+			validateErr: temporal.NewNonRetryableApplicationError("order amount exceeds limit", "ValidationFailed", nil),
+			want: []wantEvent{
+				{step: "ValidateOrder", failed: true},
+				{step: "NotifyCustomer"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(5 * time.Second).Return(tt.validateErr)
+			env.mockSuccess()
+
+			if tt.signal != "" {
+				env.RegisterDelayedCallback(func() {
+					env.SignalWorkflow(tt.signal, tt.signal)
+				}, tt.signalAt)
+			}
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+			require.True(t, env.IsWorkflowCompleted())
+
+			val, err := env.QueryWorkflow(workflows.QueryTimeline)
+			require.NoError(t, err)
+			var events []models.TimelineEvent
+			require.NoError(t, val.Get(&events))
+
+			require.Len(t, events, len(tt.want))
+			for i, want := range tt.want {
+				got := events[i]
+				assert.Equal(t, want.step, got.Step, "event %d", i)
+				assert.Equal(t, want.trigger, got.Trigger, "event %d", i)
+				assert.Equal(t, want.attempts, got.Attempts, "event %d", i)
+				assert.Equal(t, want.failed, got.Error != "", "event %d: %s", i, got.Error)
+				require.NotNil(t, got.EndedAt, "event %d", i)
+				assert.False(t, got.EndedAt.Before(got.StartedAt), "event %d", i)
+			}
+			assert.Equal(t, 5*time.Second, events[0].EndedAt.Sub(events[0].StartedAt))
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestOrderWorkflow_Amendments(t *testing.T) {
+	order := testOrder("TEST-UPD-001")
+
+	tests := []struct {
+		name            string
+		update          string
+		arg             interface{}
+		updateAt        time.Duration
+		validationDelay time.Duration
+		paymentDelay    time.Duration
+		revalidationErr error
+		wantRejected    string
+		wantErr         string
+		wantAmount      models.Money
+		wantValidations int
+	}{
+		{
+			name:            "Add Item During Validation - Revalidated",
+			update:          workflows.UpdateAddItem,
+			arg:             models.OrderItem{ProductID: "PROD-002", Name: "Product 2", Quantity: 1, Price: models.NewMoney(25000, "USD")},
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantAmount:      models.NewMoney(125000, "USD"),
+			wantValidations: 2,
+		},
+		{
+			name:            "Change Quantity During Validation - Revalidated",
+			update:          workflows.UpdateChangeQuantity,
+			arg:             workflows.QuantityChange{ProductID: "PROD-001", Quantity: 3},
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantAmount:      models.NewMoney(150000, "USD"),
+			wantValidations: 2,
+		},
+		{
+			name:            "Apply Discount During Validation - Revalidated",
+			update:          workflows.UpdateApplyDiscount,
+			arg:             "SAVE10",
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantAmount:      models.NewMoney(90000, "USD"),
+			wantValidations: 2,
+		},
+		{
+			name:            "Change Address - Amount Unchanged Skips Revalidation",
+			update:          workflows.UpdateChangeShippingAddress,
+			arg:             models.Address{Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US"},
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantAmount:      models.NewMoney(100000, "USD"),
+			wantValidations: 1,
+		},
+		{
+			name:            "Amended Order Fails Validation - Order Unchanged",
+			update:          workflows.UpdateAddItem,
+			arg:             models.OrderItem{ProductID: "PROD-002", Name: "Product 2", Quantity: 1, Price: models.NewMoney(25000, "USD")},
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			revalidationErr: temporal.NewNonRetryableApplicationError("amount exceeds limit", "ValidationFailed", nil),
+			wantErr:         "amended order failed validation",
+			wantAmount:      models.NewMoney(100000, "USD"),
+			wantValidations: 2,
+		},
+		{
+			name:            "Rejected - Payment Started",
+			update:          workflows.UpdateAddItem,
+			arg:             models.OrderItem{ProductID: "PROD-002", Name: "Product 2", Quantity: 1, Price: models.NewMoney(25000, "USD")},
+			updateAt:        5 * time.Second,
+			paymentDelay:    10 * time.Second,
+			wantRejected:    "payment has started",
+			wantAmount:      models.NewMoney(100000, "USD"),
+			wantValidations: 1,
+		},
+		{
+			name:            "Rejected - Unknown Discount Code",
+			update:          workflows.UpdateApplyDiscount,
+			arg:             "FREESTUFF",
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantRejected:    "unknown discount code",
+			wantAmount:      models.NewMoney(100000, "USD"),
+			wantValidations: 1,
+		},
+		{
+			name:            "Rejected - Removing Last Item",
+			update:          workflows.UpdateRemoveItem,
+			arg:             "PROD-001",
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantRejected:    "cannot remove the last item",
+			wantAmount:      models.NewMoney(100000, "USD"),
+			wantValidations: 1,
+		},
+		{
+			name:            "Rejected - Invalid Address",
+			update:          workflows.UpdateChangeShippingAddress,
+			arg:             models.Address{Line1: "1 Main St", Country: "US"},
+			updateAt:        time.Second,
+			validationDelay: 10 * time.Second,
+			wantRejected:    "address city is required",
+			wantAmount:      models.NewMoney(100000, "USD"),
+			wantValidations: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+
+			var validated []models.Money
+			var authorized models.Money
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(tt.validationDelay).Return(
+				func(ctx context.Context, o models.Order) error {
+					validated = append(validated, o.Amount)
+					if len(validated) > 1 {
+						return tt.revalidationErr
+					}
+					return nil
+				})
+			env.OnActivity(env.paymentAct.AuthorizePayment, mock.Anything, mock.Anything).After(tt.paymentDelay).Return(
+				func(ctx context.Context, o models.Order) (string, error) {
+					authorized = o.Amount
+					return "AUTH-1", nil
+				})
+			env.mockSuccess()
+
+			var rejectErr, updateErr error
+			var amended models.Order
+			env.RegisterDelayedCallback(func() {
+				env.UpdateWorkflow(tt.update, "", &testsuite.TestUpdateCallback{
+					OnReject: func(err error) { rejectErr = err },
+					OnAccept: func() {},
+					OnComplete: func(result interface{}, err error) {
+						updateErr = err
+						if order, ok := result.(models.Order); ok {
+							amended = order
+						}
+					},
+				}, tt.arg)
+			}, tt.updateAt)
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			switch {
+			case tt.wantRejected != "":
+				require.Error(t, rejectErr)
+				assert.Contains(t, rejectErr.Error(), tt.wantRejected)
+			case tt.wantErr != "":
+				require.NoError(t, rejectErr)
+				require.Error(t, updateErr)
+				assert.Contains(t, updateErr.Error(), tt.wantErr)
+			default:
+				require.NoError(t, rejectErr)
+				require.NoError(t, updateErr)
+				assert.Equal(t, tt.wantAmount, amended.Amount)
+			}
+
+			assert.Len(t, validated, tt.wantValidations)
+			assert.Equal(t, tt.wantAmount, authorized)
+
+			val, err := env.QueryWorkflow(workflows.QueryState)
+			require.NoError(t, err)
+			var state models.WorkflowState
+			require.NoError(t, val.Get(&state))
+			assert.Equal(t, tt.wantAmount, state.Amount)
+			assert.Equal(t, models.OrderStatusCompleted, state.Status)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"testing"
+
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder_Validate(t *testing.T) {
+	validOrder := func() models.Order {
+		return models.Order{
+			ID: "order-123",
+			Items: []models.OrderItem{
+				{ProductID: "KB-01", Name: "Keyboard", Quantity: 1, Price: models.NewMoney(12900, "USD")},
+				{ProductID: "MS-02", Name: "Mouse", Quantity: 2, Price: models.NewMoney(3450, "USD")},
+			},
+			Amount: models.NewMoney(19800, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		modify        func(o *models.Order)
+		wantErr       bool
+		errorContains string
+	}{
+		{
+			name:   "Success - Valid Order",
+			modify: func(o *models.Order) {},
+		},
+		{
+			name: "Success - Discount Code And Address",
+			modify: func(o *models.Order) {
+				o.DiscountCode = "SAVE10"
+				o.ShippingAddress = &models.Address{Line1: "1 Market Street", City: "San Francisco", PostalCode: "94105", Country: "US"}
+			},
+		},
+		{
+			name:          "Failure - Missing ID",
+			modify:        func(o *models.Order) { o.ID = "" },
+			wantErr:       true,
+			errorContains: "order ID is required",
+		},
+		{
+			name:          "Failure - No Items",
+			modify:        func(o *models.Order) { o.Items = nil },
+			wantErr:       true,
+			errorContains: "has no items",
+		},
+		{
+			name:          "Failure - Missing Product ID",
+			modify:        func(o *models.Order) { o.Items[1].ProductID = "" },
+			wantErr:       true,
+			errorContains: "item 2: product ID is required",
+		},
+		{
+			name:          "Failure - Duplicate Product ID",
+			modify:        func(o *models.Order) { o.Items[1].ProductID = "KB-01" },
+			wantErr:       true,
+			errorContains: "duplicate product ID",
+		},
+		{
+			name:          "Failure - Zero Quantity",
+			modify:        func(o *models.Order) { o.Items[0].Quantity = 0 },
+			wantErr:       true,
+			errorContains: "quantity must be positive",
+		},
+		{
+			name:          "Failure - Negative Price",
+			modify:        func(o *models.Order) { o.Items[0].Price = models.NewMoney(-100, "USD") },
+			wantErr:       true,
+			errorContains: "price must not be negative",
+		},
+		{
+			name:          "Failure - Mixed Currencies",
+			modify:        func(o *models.Order) { o.Items[1].Price = models.NewMoney(3450, "EUR") },
+			wantErr:       true,
+			errorContains: "does not match order currency USD",
+		},
+		{
+			name:          "Failure - Zero Amount",
+			modify:        func(o *models.Order) { o.Amount = models.NewMoney(0, "USD") },
+			wantErr:       true,
+			errorContains: "amount must be positive",
+		},
+		{
+			name:          "Failure - Amount Differs From Items",
+			modify:        func(o *models.Order) { o.Amount = models.NewMoney(15000, "USD") },
+			wantErr:       true,
+			errorContains: "amount 150.00 USD does not match its item total 198.00 USD",
+		},
+		{
+			name:          "Failure - Unknown Discount Code",
+			modify:        func(o *models.Order) { o.DiscountCode = "FREE" },
+			wantErr:       true,
+			errorContains: "unknown discount code",
+		},
+		{
+			name: "Failure - Invalid Address",
+			modify: func(o *models.Order) {
+				o.ShippingAddress = &models.Address{Line1: "1 Market Street", City: "San Francisco", PostalCode: "94105", Country: "USA"}
+			},
+			wantErr:       true,
+			errorContains: "ISO-3166",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := validOrder()
+			tt.modify(&order)
+
+			err := order.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
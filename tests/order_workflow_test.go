@@ -0,0 +1,426 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// testOrder returns the order of the workflow tests: two items of 500.00 USD
+func testOrder(id string) models.Order {
+	return models.Order{
+		ID:     id,
+		Amount: models.NewMoney(100000, "USD"),
+		Items: []models.OrderItem{
+			{ProductID: "PROD-001", Name: "Product 1", Quantity: 2, Price: models.NewMoney(50000, "USD")},
+		},
+	}
+}
+
+// orderTestEnv is a test environment for OrderWorkflow with the payment child workflow and
+// every activity registered
+type orderTestEnv struct {
+	*testsuite.TestWorkflowEnvironment
+	act        *activities.Activities
+	paymentAct *activities.PaymentActivities
+}
+
+// newOrderTestEnv creates an orderTestEnv from testSuite
+func newOrderTestEnv(testSuite *testsuite.WorkflowTestSuite) *orderTestEnv {
+	env := &orderTestEnv{
+		TestWorkflowEnvironment: testSuite.NewTestWorkflowEnvironment(),
+		act:                     &activities.Activities{},
+		paymentAct:              &activities.PaymentActivities{},
+	}
+	env.RegisterWorkflow(workflows.PaymentWorkflow)
+	env.RegisterActivity(env.act)
+	env.RegisterActivity(env.paymentAct)
+	return env
+}
+
+// mockSuccess makes every activity succeed at once: the payment is authorized as AUTH-1,
+// captured as TXN-1 and refunded as REFUND-1. The first matching mock is used, so a test
+// mocks the activities it checks before calling mockSuccess.
+func (e *orderTestEnv) mockSuccess() {
+	e.OnActivity(e.act.ValidateOrder, mock.Anything, mock.Anything).Return(nil)
+	e.OnActivity(e.act.ProcessOrder, mock.Anything, mock.Anything).Return(nil)
+	e.OnActivity(e.act.NotifyCustomer, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	e.OnActivity(e.act.RollbackOrder, mock.Anything, mock.Anything).Return(nil)
+	e.OnActivity(e.paymentAct.AuthorizePayment, mock.Anything, mock.Anything).Return("AUTH-1", nil)
+	e.OnActivity(e.paymentAct.CapturePayment, mock.Anything, mock.Anything, "AUTH-1").Return("TXN-1", nil)
+	e.OnActivity(e.paymentAct.VoidAuthorization, mock.Anything, "AUTH-1").Return(nil)
+	e.OnActivity(e.paymentAct.RefundPayment, mock.Anything, "TXN-1", mock.Anything).Return("REFUND-1", nil)
+}
+
+// queryState returns the workflow state with LastUpdated and Version cleared so it can be
+// compared whole
+func (e *orderTestEnv) queryState(t *testing.T) models.WorkflowState {
+	t.Helper()
+	val, err := e.QueryWorkflow(workflows.QueryState)
+	require.NoError(t, err)
+	var state models.WorkflowState
+	require.NoError(t, val.Get(&state))
+	state.LastUpdated = time.Time{}
+	state.Version = 0
+	return state
+}
+
+func TestOrderWorkflow_Compensation(t *testing.T) {
+	order := testOrder("TEST-WF-001")
+
+	tests := []struct {
+		name              string
+		legacy            bool
+		captureErr        error
+		processErr        error
+		refundErr         error
+		wantCompensations []string
+		wantRefunded      bool
+		wantFailed        []string
+	}{
+		{
+			name:              "Processing Failure - Refund Then Rollback",
+			processErr:        errors.New("inventory unavailable"),
+			wantCompensations: []string{"RefundPayment", "RollbackOrder"},
+			wantRefunded:      true,
+		},
+		{
+			name:              "Capture Failure - Void Then Rollback",
+			captureErr:        errors.New("card declined"),
+			wantCompensations: []string{"VoidAuthorization", "RollbackOrder"},
+		},
+		{
+			name:              "Refund Failure - Reported And Rollback Still Runs",
+			processErr:        errors.New("inventory unavailable"),
+			refundErr:         temporal.NewNonRetryableApplicationError("refund rejected", "RefundRejected", nil),
+			wantCompensations: []string{"RefundPayment", "RollbackOrder"},
+			wantFailed:        []string{"RefundPayment"},
+		},
+		{
+			name:              "Processing Failure Before Refunds - Rollback Only",
+			legacy:            true,
+			processErr:        errors.New("inventory unavailable"),
+			wantCompensations: []string{"RollbackOrder"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+			if tt.legacy {
+				env.OnGetVersion("refund-captured-payments", workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+			}
+
+			var compensations []string
+			env.OnActivity(env.act.ProcessOrder, mock.Anything, mock.Anything).Return(tt.processErr)
+			env.OnActivity(env.act.RollbackOrder, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, o models.Order) error {
+					compensations = append(compensations, "RollbackOrder")
+					return nil
+				})
+			env.OnActivity(env.paymentAct.CapturePayment, mock.Anything, mock.Anything, "AUTH-1").Return("TXN-1", tt.captureErr)
+			env.OnActivity(env.paymentAct.VoidAuthorization, mock.Anything, "AUTH-1").Return(
+				func(ctx context.Context, authorizationID string) error {
+					compensations = append(compensations, "VoidAuthorization")
+					return nil
+				})
+			env.OnActivity(env.paymentAct.RefundPayment, mock.Anything, "TXN-1", order.Amount).Return(
+				func(ctx context.Context, transactionID string, amount models.Money) (string, error) {
+					compensations = append(compensations, "RefundPayment")
+					return "REFUND-1", tt.refundErr
+				})
+			env.mockSuccess()
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			assert.Error(t, env.GetWorkflowError())
+			assert.Equal(t, tt.wantCompensations, compensations)
+
+			state := env.queryState(t)
+			assert.Equal(t, models.OrderStatusFailed, state.Status)
+			assert.Equal(t, tt.wantRefunded, state.Refunded)
+
+			var failed []string
+			for _, f := range state.FailedCompensations {
+				failed = append(failed, f.Step)
+			}
+			assert.Equal(t, tt.wantFailed, failed)
+			if tt.wantFailed != nil {
+				assert.Contains(t, env.GetWorkflowError().Error(), "compensations failed")
+			}
+		})
+	}
+}
+
+func TestOrderWorkflow(t *testing.T) {
+	order := testOrder("TEST-WF-002")
+
+	tests := []struct {
+		name              string
+		validateErr       error
+		authorizeErr      error
+		paymentErr        error
+		notifyErr         error
+		wantErr           string
+		wantState         models.WorkflowState
+		wantNotification  string
+		wantCompensations []string
+	}{
+		{
+			name: "Success - Completed",
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusCompleted,
+				Amount:         order.Amount,
+				ValidationDone: true,
+				PaymentDone:    true,
+				ProcessingDone: true,
+				TransactionID:  "TXN-1",
+			},
+			wantNotification: "Your order has been processed successfully",
+		},
+		{
+			name:        "Validation Failure - Nothing To Undo",
+			validateErr: temporal.NewNonRetryableApplicationError("order amount exceeds limit", "ValidationFailed", nil),
+			wantErr:     "validation failed",
+			wantState: models.WorkflowState{
+				OrderID: order.ID,
+				Status:  models.OrderStatusFailed,
+				Amount:  order.Amount,
+			},
+			wantNotification: "Order validation failed",
+		},
+		{
+			name:         "Authorization Failure - Rolled Back",
+			authorizeErr: temporal.NewNonRetryableApplicationError("card declined", "Declined", nil),
+			wantErr:      "payment authorization failed",
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusFailed,
+				Amount:         order.Amount,
+				ValidationDone: true,
+			},
+			wantNotification:  "Payment processing failed",
+			wantCompensations: []string{"RollbackOrder"},
+		},
+		{
+			name:       "Child Workflow Failure - Rolled Back",
+			paymentErr: temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_START_TO_CLOSE, nil),
+			wantErr:    "payment failed",
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusFailed,
+				Amount:         order.Amount,
+				ValidationDone: true,
+			},
+			wantNotification:  "Payment processing failed",
+			wantCompensations: []string{"RollbackOrder"},
+		},
+		{
+			name:      "Notification Failure - Still Completed",
+			notifyErr: temporal.NewNonRetryableApplicationError("mail server down", "NotifyFailed", nil),
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusCompleted,
+				Amount:         order.Amount,
+				ValidationDone: true,
+				PaymentDone:    true,
+				ProcessingDone: true,
+				TransactionID:  "TXN-1",
+			},
+			wantNotification: "Your order has been processed successfully",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+
+			var notification string
+			var compensations []string
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).Return(tt.validateErr)
+			if tt.paymentErr != nil {
+				env.OnWorkflow(workflows.PaymentWorkflow, mock.Anything, mock.Anything).Return(models.PaymentResult{}, tt.paymentErr)
+			}
+			env.OnActivity(env.paymentAct.AuthorizePayment, mock.Anything, mock.Anything).Return("AUTH-1", tt.authorizeErr)
+			env.OnActivity(env.act.NotifyCustomer, mock.Anything, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, o models.Order, message string) error {
+					notification = message
+					return tt.notifyErr
+				})
+			env.OnActivity(env.act.RollbackOrder, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, o models.Order) error {
+					compensations = append(compensations, "RollbackOrder")
+					return nil
+				})
+			env.mockSuccess()
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			if tt.wantErr != "" {
+				require.Error(t, env.GetWorkflowError())
+				assert.Contains(t, env.GetWorkflowError().Error(), tt.wantErr)
+			} else {
+				require.NoError(t, env.GetWorkflowError())
+			}
+
+			assert.Equal(t, tt.wantState, env.queryState(t))
+			assert.Equal(t, tt.wantNotification, notification)
+			assert.Equal(t, tt.wantCompensations, compensations)
+		})
+	}
+}
+
+func TestOrderWorkflow_VersionBranches(t *testing.T) {
+	order := testOrder("TEST-WF-003")
+
+	tests := []struct {
+		name              string
+		version           workflow.Version
+		cancel            bool
+		wantState         models.WorkflowState
+		wantPayment       bool
+		wantCompensations []string
+		wantRejected      string
+	}{
+		{
+			name:    "Default Version - No Payment",
+			version: workflow.DefaultVersion,
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusCompleted,
+				Amount:         order.Amount,
+				ValidationDone: true,
+				ProcessingDone: true,
+			},
+			wantRejected: "processing has started",
+		},
+		{
+			name:    "Default Version - Cancel Rolls Back Only",
+			version: workflow.DefaultVersion,
+			cancel:  true,
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusCancelled,
+				Amount:         order.Amount,
+				ValidationDone: true,
+			},
+			wantCompensations: []string{"RollbackOrder"},
+			wantRejected:      "order was cancelled",
+		},
+		{
+			name:    "Version 1 - Payment Processed",
+			version: 1,
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusCompleted,
+				Amount:         order.Amount,
+				ValidationDone: true,
+				PaymentDone:    true,
+				ProcessingDone: true,
+				TransactionID:  "TXN-1",
+			},
+			wantPayment:  true,
+			wantRejected: "payment has started",
+		},
+		{
+			name:    "Version 1 - Cancel Refunds And Rolls Back",
+			version: 1,
+			cancel:  true,
+			wantState: models.WorkflowState{
+				OrderID:        order.ID,
+				Status:         models.OrderStatusCancelled,
+				Amount:         order.Amount,
+				ValidationDone: true,
+				PaymentDone:    true,
+				TransactionID:  "TXN-1",
+				Refunded:       true,
+			},
+			wantPayment:       true,
+			wantCompensations: []string{"RefundPayment", "RollbackOrder"},
+			wantRejected:      "order was cancelled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOrderTestEnv(&testsuite.WorkflowTestSuite{})
+			env.OnGetVersion("add-payment-processing", workflow.DefaultVersion, 1).Return(tt.version)
+
+			payments := 0
+			var compensations []string
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).After(10 * time.Second).Return(nil)
+			env.OnActivity(env.paymentAct.AuthorizePayment, mock.Anything, mock.Anything).After(10 * time.Second).Return(
+				func(ctx context.Context, o models.Order) (string, error) {
+					payments++
+					return "AUTH-1", nil
+				})
+			env.OnActivity(env.act.ProcessOrder, mock.Anything, mock.Anything).After(10 * time.Second).Return(nil)
+			env.OnActivity(env.act.RollbackOrder, mock.Anything, mock.Anything).Return(
+				func(ctx context.Context, o models.Order) error {
+					compensations = append(compensations, "RollbackOrder")
+					return nil
+				})
+			env.OnActivity(env.paymentAct.RefundPayment, mock.Anything, "TXN-1", order.Amount).Return(
+				func(ctx context.Context, transactionID string, amount models.Money) (string, error) {
+					compensations = append(compensations, "RefundPayment")
+					return "REFUND-1", nil
+				})
+			env.mockSuccess()
+
+			// Cancel while payment runs on version 1; on the default version the
+			// order is already processing by then, so cancel during validation instead
+			updateAt := 12 * time.Second
+			if tt.cancel {
+				cancelAt := 15 * time.Second
+				if tt.version == workflow.DefaultVersion {
+					cancelAt = 5 * time.Second
+				}
+				env.RegisterDelayedCallback(func() {
+					env.SignalWorkflow(workflows.SignalCancel, "cancel")
+				}, cancelAt)
+				updateAt = cancelAt + time.Second
+			}
+
+			// Amendments are closed once the order moves past validation or is cancelled
+			var rejectErr error
+			env.RegisterDelayedCallback(func() {
+				env.UpdateWorkflow(workflows.UpdateApplyDiscount, "", &testsuite.TestUpdateCallback{
+					OnReject:   func(err error) { rejectErr = err },
+					OnAccept:   func() {},
+					OnComplete: func(interface{}, error) {},
+				}, "SAVE10")
+			}, updateAt)
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			if tt.cancel {
+				require.Error(t, env.GetWorkflowError())
+			} else {
+				require.NoError(t, env.GetWorkflowError())
+			}
+
+			assert.Equal(t, tt.wantState, env.queryState(t))
+			assert.Equal(t, tt.wantPayment, payments == 1)
+			assert.Equal(t, tt.wantCompensations, compensations)
+			require.Error(t, rejectErr)
+			assert.Contains(t, rejectErr.Error(), tt.wantRejected)
+		})
+	}
+}
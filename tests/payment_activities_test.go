@@ -1,13 +1,17 @@
 package tests
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"temporal-order-system/activities"
+	"temporal-order-system/gateway"
 	"temporal-order-system/models"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
 )
 
@@ -23,7 +27,7 @@ func TestAuthorizePayment(t *testing.T) {
 			name: "Success - Valid Amount",
 			order: models.Order{
 				ID:     "TEST-PAY-001",
-				Amount: 1000.0,
+				Amount: models.NewMoney(100000, "USD"),
 			},
 			wantErr: false,
 			validateResult: func(t *testing.T, authID string) {
@@ -35,7 +39,7 @@ func TestAuthorizePayment(t *testing.T) {
 			name: "Failure - Zero Amount",
 			order: models.Order{
 				ID:     "TEST-PAY-002",
-				Amount: 0.0,
+				Amount: models.NewMoney(0, "USD"),
 			},
 			wantErr:       true,
 			errorContains: "invalid payment amount",
@@ -44,7 +48,7 @@ func TestAuthorizePayment(t *testing.T) {
 			name: "Failure - Negative Amount",
 			order: models.Order{
 				ID:     "TEST-PAY-003",
-				Amount: -100.0,
+				Amount: models.NewMoney(-10000, "USD"),
 			},
 			wantErr:       true,
 			errorContains: "invalid payment amount",
@@ -53,7 +57,7 @@ func TestAuthorizePayment(t *testing.T) {
 			name: "Failure - Exceeds Authorization Limit",
 			order: models.Order{
 				ID:     "TEST-PAY-004",
-				Amount: 60000.0,
+				Amount: models.NewMoney(6000000, "USD"),
 			},
 			wantErr:       true,
 			errorContains: "exceeds authorization limit",
@@ -62,7 +66,7 @@ func TestAuthorizePayment(t *testing.T) {
 			name: "Success - Maximum Valid Amount",
 			order: models.Order{
 				ID:     "TEST-PAY-005",
-				Amount: 9999.0,
+				Amount: models.NewMoney(999900, "USD"),
 			},
 			wantErr: false,
 			validateResult: func(t *testing.T, authID string) {
@@ -77,7 +81,7 @@ func TestAuthorizePayment(t *testing.T) {
 			testSuite := &testsuite.WorkflowTestSuite{}
 			env := testSuite.NewTestActivityEnvironment()
 
-			paymentAct := activities.NewPaymentActivities()
+			paymentAct := activities.NewPaymentActivities(gateway.NewFakeGateway())
 			env.RegisterActivity(paymentAct.AuthorizePayment)
 
 			val, err := env.ExecuteActivity(paymentAct.AuthorizePayment, tt.order)
@@ -121,7 +125,7 @@ func TestCapturePayment(t *testing.T) {
 			name: "Success - Valid Authorization",
 			order: models.Order{
 				ID:     "TEST-PAY-006",
-				Amount: 1500.0,
+				Amount: models.NewMoney(150000, "USD"),
 			},
 			setupAuth: true,
 			wantErr:   false,
@@ -134,7 +138,7 @@ func TestCapturePayment(t *testing.T) {
 			name: "Failure - Empty Authorization ID",
 			order: models.Order{
 				ID:     "TEST-PAY-007",
-				Amount: 1000.0,
+				Amount: models.NewMoney(100000, "USD"),
 			},
 			authID:        "",
 			setupAuth:     false,
@@ -142,18 +146,15 @@ func TestCapturePayment(t *testing.T) {
 			errorContains: "invalid authorization ID",
 		},
 		{
-			name: "Success - Any Non-Empty Authorization ID",
+			name: "Failure - Unknown Authorization ID",
 			order: models.Order{
 				ID:     "TEST-PAY-008",
-				Amount: 1000.0,
-			},
-			authID:    "ANY-AUTH-ID",
-			setupAuth: false,
-			wantErr:   false,
-			validateResult: func(t *testing.T, txnID string) {
-				assert.NotEmpty(t, txnID)
-				assert.Contains(t, txnID, "TXN-")
+				Amount: models.NewMoney(100000, "USD"),
 			},
+			authID:        "ANY-AUTH-ID",
+			setupAuth:     false,
+			wantErr:       true,
+			errorContains: "payment not found",
 		},
 	}
 
@@ -162,7 +163,7 @@ func TestCapturePayment(t *testing.T) {
 			testSuite := &testsuite.WorkflowTestSuite{}
 			env := testSuite.NewTestActivityEnvironment()
 
-			paymentAct := activities.NewPaymentActivities()
+			paymentAct := activities.NewPaymentActivities(gateway.NewFakeGateway())
 			env.RegisterActivity(paymentAct.AuthorizePayment)
 			env.RegisterActivity(paymentAct.CapturePayment)
 
@@ -218,22 +219,24 @@ func TestVoidAuthorization(t *testing.T) {
 			name: "Success - Valid Authorization",
 			order: models.Order{
 				ID:     "TEST-PAY-009",
-				Amount: 1000.0,
+				Amount: models.NewMoney(100000, "USD"),
 			},
 			setupAuth: true,
 			wantErr:   false,
 		},
 		{
-			name:      "Success - Empty Authorization ID (No Validation)",
-			authID:    "",
-			setupAuth: false,
-			wantErr:   false,
+			name:          "Failure - Empty Authorization ID",
+			authID:        "",
+			setupAuth:     false,
+			wantErr:       true,
+			errorContains: "payment not found",
 		},
 		{
-			name:      "Success - Any Authorization ID",
-			authID:    "SOME-AUTH-ID",
-			setupAuth: false,
-			wantErr:   false,
+			name:          "Failure - Unknown Authorization ID",
+			authID:        "SOME-AUTH-ID",
+			setupAuth:     false,
+			wantErr:       true,
+			errorContains: "payment not found",
 		},
 	}
 
@@ -242,7 +245,7 @@ func TestVoidAuthorization(t *testing.T) {
 			testSuite := &testsuite.WorkflowTestSuite{}
 			env := testSuite.NewTestActivityEnvironment()
 
-			paymentAct := activities.NewPaymentActivities()
+			paymentAct := activities.NewPaymentActivities(gateway.NewFakeGateway())
 			env.RegisterActivity(paymentAct.AuthorizePayment)
 			env.RegisterActivity(paymentAct.VoidAuthorization)
 
@@ -277,53 +280,64 @@ func TestVoidAuthorization(t *testing.T) {
 }
 
 func TestRefundPayment(t *testing.T) {
+	capturedOrder := models.Order{
+		ID:     "TEST-PAY-010",
+		Amount: models.NewMoney(100000, "USD"),
+	}
+
 	tests := []struct {
 		name           string
 		transactionID  string
-		amount         float64
+		setupCapture   bool
+		amount         models.Money
 		wantErr        bool
 		errorContains  string
 		validateResult func(t *testing.T, refundID string)
 	}{
 		{
-			name:          "Success - Valid Refund",
-			transactionID: "TXN-12345",
-			amount:        500.0,
-			wantErr:       false,
+			name:         "Success - Valid Refund",
+			setupCapture: true,
+			amount:       models.NewMoney(50000, "USD"),
+			wantErr:      false,
 			validateResult: func(t *testing.T, refundID string) {
 				assert.NotEmpty(t, refundID)
 				assert.Contains(t, refundID, "REFUND-")
 			},
 		},
 		{
-			name:          "Success - Empty Transaction ID (No Validation)",
+			name:          "Failure - Empty Transaction ID",
 			transactionID: "",
-			amount:        500.0,
-			wantErr:       false,
-			validateResult: func(t *testing.T, refundID string) {
-				assert.NotEmpty(t, refundID)
-				assert.Contains(t, refundID, "REFUND-")
-			},
+			amount:        models.NewMoney(50000, "USD"),
+			wantErr:       true,
+			errorContains: "payment not found",
 		},
 		{
-			name:          "Success - Zero Amount (No Validation)",
+			name:          "Failure - Unknown Transaction ID",
 			transactionID: "TXN-12345",
-			amount:        0.0,
-			wantErr:       false,
-			validateResult: func(t *testing.T, refundID string) {
-				assert.NotEmpty(t, refundID)
-				assert.Contains(t, refundID, "REFUND-")
-			},
+			amount:        models.NewMoney(50000, "USD"),
+			wantErr:       true,
+			errorContains: "payment not found",
 		},
 		{
-			name:          "Success - Negative Amount (No Validation)",
-			transactionID: "TXN-12345",
-			amount:        -100.0,
-			wantErr:       false,
-			validateResult: func(t *testing.T, refundID string) {
-				assert.NotEmpty(t, refundID)
-				assert.Contains(t, refundID, "REFUND-")
-			},
+			name:          "Failure - Zero Amount",
+			setupCapture:  true,
+			amount:        models.NewMoney(0, "USD"),
+			wantErr:       true,
+			errorContains: "invalid refund amount",
+		},
+		{
+			name:          "Failure - Negative Amount",
+			setupCapture:  true,
+			amount:        models.NewMoney(-10000, "USD"),
+			wantErr:       true,
+			errorContains: "invalid refund amount",
+		},
+		{
+			name:          "Failure - Exceeds Captured Amount",
+			setupCapture:  true,
+			amount:        models.NewMoney(150000, "USD"),
+			wantErr:       true,
+			errorContains: "exceeds remaining",
 		},
 	}
 
@@ -332,10 +346,25 @@ func TestRefundPayment(t *testing.T) {
 			testSuite := &testsuite.WorkflowTestSuite{}
 			env := testSuite.NewTestActivityEnvironment()
 
-			paymentAct := activities.NewPaymentActivities()
+			paymentAct := activities.NewPaymentActivities(gateway.NewFakeGateway())
+			env.RegisterActivity(paymentAct.AuthorizePayment)
+			env.RegisterActivity(paymentAct.CapturePayment)
 			env.RegisterActivity(paymentAct.RefundPayment)
 
-			val, err := env.ExecuteActivity(paymentAct.RefundPayment, tt.transactionID, tt.amount)
+			transactionID := tt.transactionID
+			if tt.setupCapture {
+				// First authorize and capture the payment
+				val, err := env.ExecuteActivity(paymentAct.AuthorizePayment, capturedOrder)
+				require.NoError(t, err)
+				var authID string
+				require.NoError(t, val.Get(&authID))
+
+				val, err = env.ExecuteActivity(paymentAct.CapturePayment, capturedOrder, authID)
+				require.NoError(t, err)
+				require.NoError(t, val.Get(&transactionID))
+			}
+
+			val, err := env.ExecuteActivity(paymentAct.RefundPayment, transactionID, tt.amount)
 
 			// ExecuteActivity itself can fail for some activities
 			if err != nil && tt.wantErr {
@@ -368,7 +397,7 @@ func TestPaymentWorkflow_FullFlow(t *testing.T) {
 	tests := []struct {
 		name          string
 		order         models.Order
-		refundAmount  float64
+		refundAmount  models.Money
 		wantErr       bool
 		errorContains string
 	}{
@@ -376,27 +405,27 @@ func TestPaymentWorkflow_FullFlow(t *testing.T) {
 			name: "Success - Complete Flow",
 			order: models.Order{
 				ID:     "TEST-PAY-FLOW-001",
-				Amount: 2500.0,
+				Amount: models.NewMoney(250000, "USD"),
 			},
-			refundAmount: 2500.0,
+			refundAmount: models.NewMoney(250000, "USD"),
 			wantErr:      false,
 		},
 		{
 			name: "Success - Partial Refund",
 			order: models.Order{
 				ID:     "TEST-PAY-FLOW-002",
-				Amount: 5000.0,
+				Amount: models.NewMoney(500000, "USD"),
 			},
-			refundAmount: 2500.0,
+			refundAmount: models.NewMoney(250000, "USD"),
 			wantErr:      false,
 		},
 		{
 			name: "Success - Small Amount",
 			order: models.Order{
 				ID:     "TEST-PAY-FLOW-003",
-				Amount: 50.0,
+				Amount: models.NewMoney(5000, "USD"),
 			},
-			refundAmount: 50.0,
+			refundAmount: models.NewMoney(5000, "USD"),
 			wantErr:      false,
 		},
 	}
@@ -406,7 +435,7 @@ func TestPaymentWorkflow_FullFlow(t *testing.T) {
 			testSuite := &testsuite.WorkflowTestSuite{}
 			env := testSuite.NewTestActivityEnvironment()
 
-			paymentAct := activities.NewPaymentActivities()
+			paymentAct := activities.NewPaymentActivities(gateway.NewFakeGateway())
 			env.RegisterActivity(paymentAct.AuthorizePayment)
 			env.RegisterActivity(paymentAct.CapturePayment)
 			env.RegisterActivity(paymentAct.RefundPayment)
@@ -445,3 +474,73 @@ func TestPaymentWorkflow_FullFlow(t *testing.T) {
 		})
 	}
 }
+
+// unreachableGateway fails every call the way an HTTP gateway fails when the processor is down
+type unreachableGateway struct {
+	gateway.PaymentGateway
+}
+
+func (g unreachableGateway) Authorize(ctx context.Context, req gateway.AuthorizeRequest) (gateway.Payment, error) {
+	return gateway.Payment{}, errors.New("dial tcp: connection refused")
+}
+
+func TestPaymentActivities_GatewayErrors(t *testing.T) {
+	order := models.Order{
+		ID:     "TEST-PAY-ERR-001",
+		Amount: models.NewMoney(100000, "USD"),
+	}
+
+	tests := []struct {
+		name          string
+		gateway       gateway.PaymentGateway
+		order         models.Order
+		capture       bool
+		wantType      string
+		wantRetryable bool
+	}{
+		{
+			name:     "Declined - Not Retried",
+			gateway:  gateway.NewFakeGateway(),
+			order:    models.Order{ID: "TEST-PAY-ERR-002", Amount: models.NewMoney(6000000, "USD")},
+			wantType: activities.ErrTypePaymentDeclined,
+		},
+		{
+			name:     "Not Found - Not Retried",
+			gateway:  gateway.NewFakeGateway(),
+			order:    order,
+			capture:  true,
+			wantType: activities.ErrTypePaymentNotFound,
+		},
+		{
+			name:          "Transport Failure - Retried",
+			gateway:       unreachableGateway{},
+			order:         order,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSuite := &testsuite.WorkflowTestSuite{}
+			env := testSuite.NewTestActivityEnvironment()
+
+			paymentAct := activities.NewPaymentActivities(tt.gateway)
+			env.RegisterActivity(paymentAct)
+
+			var err error
+			if tt.capture {
+				_, err = env.ExecuteActivity(paymentAct.CapturePayment, tt.order, "AUTH-UNKNOWN")
+			} else {
+				_, err = env.ExecuteActivity(paymentAct.AuthorizePayment, tt.order)
+			}
+			require.Error(t, err)
+
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr), err.Error())
+			assert.Equal(t, tt.wantRetryable, !appErr.NonRetryable())
+			if tt.wantType != "" {
+				assert.Equal(t, tt.wantType, appErr.Type())
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"temporal-order-system/gateway"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPGateway(t *testing.T) {
+	tests := []struct {
+		name          string
+		call          func(g *gateway.HTTPGateway) (gateway.Payment, error)
+		mockHandler   func(t *testing.T, w http.ResponseWriter, r *http.Request)
+		wantPayment   gateway.Payment
+		wantErr       error
+		errorContains string
+	}{
+		{
+			name: "Success - Authorize",
+			call: func(g *gateway.HTTPGateway) (gateway.Payment, error) {
+				return g.Authorize(context.Background(), gateway.AuthorizeRequest{IdempotencyKey: "wf/1/TEST-GW-001", OrderID: "TEST-GW-001", Amount: models.NewMoney(25000, "USD")})
+			},
+			mockHandler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/payments/authorize", r.URL.Path)
+				assert.Equal(t, "wf/1/TEST-GW-001", r.Header.Get(gateway.IdempotencyKeyHeader))
+
+				var req gateway.AuthorizeRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "TEST-GW-001", req.OrderID)
+				assert.Equal(t, models.NewMoney(25000, "USD"), req.Amount)
+
+				json.NewEncoder(w).Encode(gateway.Payment{ID: "AUTH-1", OrderID: req.OrderID, Status: gateway.PaymentStatusAuthorized, Amount: req.Amount})
+			},
+			wantPayment: gateway.Payment{ID: "AUTH-1", OrderID: "TEST-GW-001", Status: gateway.PaymentStatusAuthorized, Amount: models.NewMoney(25000, "USD")},
+		},
+		{
+			name: "Success - Status Lookup",
+			call: func(g *gateway.HTTPGateway) (gateway.Payment, error) {
+				return g.Status(context.Background(), "TXN-1")
+			},
+			mockHandler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				assert.Equal(t, "/payments/TXN-1", r.URL.Path)
+				json.NewEncoder(w).Encode(gateway.Payment{ID: "TXN-1", Status: gateway.PaymentStatusCaptured, Amount: models.NewMoney(10000, "USD")})
+			},
+			wantPayment: gateway.Payment{ID: "TXN-1", Status: gateway.PaymentStatusCaptured, Amount: models.NewMoney(10000, "USD")},
+		},
+		{
+			name: "Failure - Declined",
+			call: func(g *gateway.HTTPGateway) (gateway.Payment, error) {
+				return g.Capture(context.Background(), gateway.CaptureRequest{AuthorizationID: "AUTH-1", Amount: models.NewMoney(10000, "USD")})
+			},
+			mockHandler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusPaymentRequired)
+				w.Write([]byte("insufficient funds"))
+			},
+			wantErr:       gateway.ErrDeclined,
+			errorContains: "insufficient funds",
+		},
+		{
+			name: "Failure - Not Found",
+			call: func(g *gateway.HTTPGateway) (gateway.Payment, error) {
+				return g.Refund(context.Background(), gateway.RefundRequest{TransactionID: "TXN-404", Amount: models.NewMoney(10000, "USD")})
+			},
+			mockHandler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr: gateway.ErrNotFound,
+		},
+		{
+			name: "Failure - Server Error",
+			call: func(g *gateway.HTTPGateway) (gateway.Payment, error) {
+				return g.Authorize(context.Background(), gateway.AuthorizeRequest{OrderID: "TEST-GW-002", Amount: models.NewMoney(10000, "USD")})
+			},
+			mockHandler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("Internal Server Error"))
+			},
+			errorContains: "status 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.mockHandler(t, w, r)
+			}))
+			defer mockServer.Close()
+
+			payment, err := tt.call(gateway.NewHTTPGateway(mockServer.URL))
+
+			if tt.wantErr == nil && tt.errorContains == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantPayment, payment)
+				return
+			}
+
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.errorContains != "" {
+				assert.Contains(t, err.Error(), tt.errorContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/gateway"
+	"temporal-order-system/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// lossyGateway applies every operation to the fake gateway but loses the response of
+// the first call of each operation, as a network timeout would
+type lossyGateway struct {
+	*gateway.FakeGateway
+
+	mu    sync.Mutex
+	calls map[string]int
+	keys  map[string][]string
+}
+
+func newLossyGateway() *lossyGateway {
+	return &lossyGateway{
+		FakeGateway: gateway.NewFakeGateway(),
+		calls:       make(map[string]int),
+		keys:        make(map[string][]string),
+	}
+}
+
+func (g *lossyGateway) observe(op, key string, err error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.calls[op]++
+	g.keys[op] = append(g.keys[op], key)
+	if err == nil && g.calls[op] == 1 {
+		return errors.New("connection reset by peer")
+	}
+	return err
+}
+
+func (g *lossyGateway) Authorize(ctx context.Context, req gateway.AuthorizeRequest) (gateway.Payment, error) {
+	payment, err := g.FakeGateway.Authorize(ctx, req)
+	return payment, g.observe("Authorize", req.IdempotencyKey, err)
+}
+
+func (g *lossyGateway) Capture(ctx context.Context, req gateway.CaptureRequest) (gateway.Payment, error) {
+	payment, err := g.FakeGateway.Capture(ctx, req)
+	return payment, g.observe("Capture", req.IdempotencyKey, err)
+}
+
+func (g *lossyGateway) Void(ctx context.Context, req gateway.VoidRequest) error {
+	return g.observe("Void", req.IdempotencyKey, g.FakeGateway.Void(ctx, req))
+}
+
+func (g *lossyGateway) Refund(ctx context.Context, req gateway.RefundRequest) (gateway.Payment, error) {
+	payment, err := g.FakeGateway.Refund(ctx, req)
+	return payment, g.observe("Refund", req.IdempotencyKey, err)
+}
+
+// countPayments counts gateway records by ID prefix
+func countPayments(payments []gateway.Payment, prefix string) int {
+	count := 0
+	for _, p := range payments {
+		if len(p.ID) >= len(prefix) && p.ID[:len(prefix)] == prefix {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPaymentActivities_RetryHasExactlyOneEffect(t *testing.T) {
+	order := models.Order{
+		ID:     "TEST-IDEM-001",
+		Amount: models.NewMoney(80000, "USD"),
+	}
+
+	tests := []struct {
+		name      string
+		void      bool
+		wantCalls map[string]int
+		verify    func(t *testing.T, payments []gateway.Payment)
+	}{
+		{
+			name:      "Authorize, Capture And Refund",
+			wantCalls: map[string]int{"Authorize": 2, "Capture": 2, "Refund": 2},
+			verify: func(t *testing.T, payments []gateway.Payment) {
+				assert.Equal(t, 1, countPayments(payments, "AUTH-"))
+				assert.Equal(t, 1, countPayments(payments, "TXN-"))
+				assert.Equal(t, 1, countPayments(payments, "REFUND-"))
+				assert.Len(t, payments, 3)
+			},
+		},
+		{
+			name:      "Authorize And Void",
+			void:      true,
+			wantCalls: map[string]int{"Authorize": 2, "Void": 2},
+			verify: func(t *testing.T, payments []gateway.Payment) {
+				require.Len(t, payments, 1)
+				assert.Equal(t, gateway.PaymentStatusVoided, payments[0].Status)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSuite := &testsuite.WorkflowTestSuite{}
+			env := testSuite.NewTestWorkflowEnvironment()
+
+			gw := newLossyGateway()
+			paymentAct := activities.NewPaymentActivities(gw)
+			env.RegisterActivity(paymentAct)
+
+			paymentFlow := func(ctx workflow.Context, order models.Order, void bool) error {
+				ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+					StartToCloseTimeout: 10 * time.Second,
+					RetryPolicy: &temporal.RetryPolicy{
+						InitialInterval: 100 * time.Millisecond,
+						MaximumAttempts: 3,
+					},
+				})
+
+				var authorizationID string
+				if err := workflow.ExecuteActivity(ctx, paymentAct.AuthorizePayment, order).Get(ctx, &authorizationID); err != nil {
+					return err
+				}
+				if void {
+					return workflow.ExecuteActivity(ctx, paymentAct.VoidAuthorization, authorizationID).Get(ctx, nil)
+				}
+
+				var transactionID string
+				if err := workflow.ExecuteActivity(ctx, paymentAct.CapturePayment, order, authorizationID).Get(ctx, &transactionID); err != nil {
+					return err
+				}
+				return workflow.ExecuteActivity(ctx, paymentAct.RefundPayment, transactionID, order.Amount).Get(ctx, nil)
+			}
+			env.RegisterWorkflow(paymentFlow)
+
+			env.ExecuteWorkflow(paymentFlow, order, tt.void)
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			assert.Equal(t, tt.wantCalls, gw.calls)
+			for op, keys := range gw.keys {
+				require.Len(t, keys, 2, op)
+				assert.Equal(t, keys[0], keys[1], "%s retried with a different idempotency key", op)
+				assert.NotEmpty(t, keys[0], op)
+			}
+			tt.verify(t, gw.Payments())
+		})
+	}
+}
+
+func TestPaymentActivities_LedgerReplaysResult(t *testing.T) {
+	order := models.Order{
+		ID:     "TEST-IDEM-002",
+		Amount: models.NewMoney(30000, "USD"),
+	}
+
+	tests := []struct {
+		name     string
+		activity func(p *activities.PaymentActivities, fake *gateway.FakeGateway) (interface{}, []interface{})
+	}{
+		{
+			name: "AuthorizePayment",
+			activity: func(p *activities.PaymentActivities, fake *gateway.FakeGateway) (interface{}, []interface{}) {
+				return p.AuthorizePayment, []interface{}{order}
+			},
+		},
+		{
+			name: "CapturePayment",
+			activity: func(p *activities.PaymentActivities, fake *gateway.FakeGateway) (interface{}, []interface{}) {
+				auth, err := fake.Authorize(context.Background(), gateway.AuthorizeRequest{OrderID: order.ID, Amount: order.Amount})
+				require.NoError(t, err)
+				return p.CapturePayment, []interface{}{order, auth.ID}
+			},
+		},
+		{
+			name: "VoidAuthorization",
+			activity: func(p *activities.PaymentActivities, fake *gateway.FakeGateway) (interface{}, []interface{}) {
+				auth, err := fake.Authorize(context.Background(), gateway.AuthorizeRequest{OrderID: order.ID, Amount: order.Amount})
+				require.NoError(t, err)
+				return p.VoidAuthorization, []interface{}{auth.ID}
+			},
+		},
+		{
+			name: "RefundPayment",
+			activity: func(p *activities.PaymentActivities, fake *gateway.FakeGateway) (interface{}, []interface{}) {
+				auth, err := fake.Authorize(context.Background(), gateway.AuthorizeRequest{OrderID: order.ID, Amount: order.Amount})
+				require.NoError(t, err)
+				txn, err := fake.Capture(context.Background(), gateway.CaptureRequest{AuthorizationID: auth.ID, Amount: order.Amount})
+				require.NoError(t, err)
+				return p.RefundPayment, []interface{}{txn.ID, models.NewMoney(10000, "USD")}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := gateway.NewFakeGateway()
+			paymentAct := activities.NewPaymentActivities(fake)
+			activityFn, args := tt.activity(paymentAct, fake)
+			before := len(fake.Payments())
+
+			// A fresh environment reuses the same workflow and activity IDs,
+			// so the second execution is a replay of the first attempt
+			var results []string
+			for attempt := 0; attempt < 2; attempt++ {
+				testSuite := &testsuite.WorkflowTestSuite{}
+				env := testSuite.NewTestActivityEnvironment()
+				env.RegisterActivity(paymentAct)
+
+				val, err := env.ExecuteActivity(activityFn, args...)
+				require.NoError(t, err)
+
+				var result string
+				if val.HasValue() {
+					require.NoError(t, val.Get(&result))
+				}
+				results = append(results, result)
+			}
+
+			assert.Equal(t, results[0], results[1])
+			assert.LessOrEqual(t, len(fake.Payments())-before, 1)
+		})
+	}
+}
+
+func TestMemoryLedger(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		maxEntries int
+		keys       []string
+		wait       time.Duration
+		want       map[string]bool
+		wantLen    int
+	}{
+		{
+			name:       "Success - Recorded Results Found",
+			ttl:        time.Hour,
+			maxEntries: 10,
+			keys:       []string{"a", "b", "c"},
+			want:       map[string]bool{"a": true, "b": true, "c": true},
+			wantLen:    3,
+		},
+		{
+			name:       "Evicted - Oldest Above Size Limit",
+			ttl:        time.Hour,
+			maxEntries: 2,
+			keys:       []string{"a", "b", "c"},
+			want:       map[string]bool{"a": false, "b": true, "c": true},
+			wantLen:    2,
+		},
+		{
+			name:       "Expired - Older Than TTL",
+			ttl:        10 * time.Millisecond,
+			maxEntries: 10,
+			keys:       []string{"a", "b"},
+			wait:       20 * time.Millisecond,
+			want:       map[string]bool{"a": false, "b": false},
+			wantLen:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := activities.NewMemoryLedgerWithLimits(tt.ttl, tt.maxEntries)
+			for _, key := range tt.keys {
+				ledger.Record(key, "result-"+key)
+			}
+			time.Sleep(tt.wait)
+
+			for key, found := range tt.want {
+				result, ok := ledger.Lookup(key)
+				assert.Equal(t, found, ok, key)
+				if found {
+					assert.Equal(t, "result-"+key, result)
+				}
+			}
+			assert.Equal(t, tt.wantLen, ledger.Len())
+
+			// Recording evicts the expired results
+			if tt.wait > 0 {
+				ledger.Record("d", "result-d")
+				assert.Equal(t, 1, ledger.Len())
+			}
+		})
+	}
+}
+
+func TestIdempotencyKey_IncludesRunID(t *testing.T) {
+	keyActivity := func(ctx context.Context) ([]string, error) {
+		info := activity.GetInfo(ctx)
+		return []string{activities.IdempotencyKey(ctx, "ORDER-1"), info.WorkflowExecution.RunID}, nil
+	}
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(keyActivity)
+
+	val, err := env.ExecuteActivity(keyActivity)
+	require.NoError(t, err)
+	var got []string
+	require.NoError(t, val.Get(&got))
+	require.NotEmpty(t, got[1])
+	assert.Contains(t, got[0], "/"+got[1]+"/")
+	assert.True(t, strings.HasSuffix(got[0], "/ORDER-1"), got[0])
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestPaymentWorkflow(t *testing.T) {
+	order := models.Order{
+		ID:     "TEST-PWF-001",
+		Amount: models.NewMoney(75000, "USD"),
+	}
+
+	tests := []struct {
+		name         string
+		authorizeErr error
+		captureErr   error
+		voidErr      error
+		wantResult   models.PaymentResult
+		wantErr      string
+		wantErrType  string
+		wantVoided   bool
+	}{
+		{
+			name: "Success - Authorized And Captured",
+			wantResult: models.PaymentResult{
+				AuthorizationID: "AUTH-1",
+				TransactionID:   "TXN-1",
+				Amount:          order.Amount,
+			},
+		},
+		{
+			name:         "Authorization Failure - Nothing To Void",
+			authorizeErr: temporal.NewNonRetryableApplicationError("card declined", "Declined", nil),
+			wantErr:      "payment authorization failed",
+		},
+		{
+			name:       "Capture Failure - Authorization Voided",
+			captureErr: temporal.NewNonRetryableApplicationError("capture rejected", "Declined", nil),
+			wantErr:    "payment capture failed",
+			wantVoided: true,
+		},
+		{
+			name:        "Capture And Void Failure - Compensation Failure Reported",
+			captureErr:  temporal.NewNonRetryableApplicationError("capture rejected", "Declined", nil),
+			voidErr:     temporal.NewNonRetryableApplicationError("void rejected", "VoidRejected", nil),
+			wantErr:     "payment capture failed",
+			wantErrType: workflows.ErrTypeCompensationFailed,
+			wantVoided:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSuite := &testsuite.WorkflowTestSuite{}
+			env := testSuite.NewTestWorkflowEnvironment()
+
+			paymentAct := &activities.PaymentActivities{}
+			env.RegisterActivity(paymentAct)
+
+			voided := false
+			env.OnActivity(paymentAct.AuthorizePayment, mock.Anything, order).Return("AUTH-1", tt.authorizeErr)
+			env.OnActivity(paymentAct.CapturePayment, mock.Anything, order, "AUTH-1").Return("TXN-1", tt.captureErr)
+			env.OnActivity(paymentAct.VoidAuthorization, mock.Anything, "AUTH-1").Return(
+				func(ctx context.Context, authorizationID string) error {
+					voided = true
+					return tt.voidErr
+				})
+
+			env.ExecuteWorkflow(workflows.PaymentWorkflow, order)
+
+			require.True(t, env.IsWorkflowCompleted())
+			assert.Equal(t, tt.wantVoided, voided)
+
+			if tt.wantErr == "" {
+				require.NoError(t, env.GetWorkflowError())
+				var result models.PaymentResult
+				require.NoError(t, env.GetWorkflowResult(&result))
+				assert.Equal(t, tt.wantResult, result)
+				return
+			}
+
+			err := env.GetWorkflowError()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			if tt.wantErrType != "" {
+				var appErr *temporal.ApplicationError
+				require.True(t, errors.As(err, &appErr))
+				assert.Equal(t, tt.wantErrType, appErr.Type())
+			}
+		})
+	}
+}
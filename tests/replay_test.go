@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// historyGenerations are the generations of the workflow code whose histories have to keep
+// replaying, oldest first: runs started before the payment step, with its add-payment-processing
+// marker, and with the refund-captured-payments marker
+var historyGenerations = []string{"pre-payment", "add-payment-processing", "refund-captured-payments"}
+
+// historyCorpus lists the captured workflow histories, one directory per generation of the
+// workflow code, written by `go run capture/capture.go`
+func historyCorpus(t *testing.T) []string {
+	var files []string
+	for _, generation := range historyGenerations {
+		generationFiles, err := filepath.Glob(filepath.Join("testdata", "histories", generation, "*.json"))
+		require.NoError(t, err)
+		require.NotEmpty(t, generationFiles, "no histories in testdata/histories/%s, see testdata/histories/README.md to capture them", generation)
+		files = append(files, generationFiles...)
+	}
+	return files
+}
+
+// workflowType returns the type of the workflow whose history is in file
+func workflowType(t *testing.T, file string) string {
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	history, err := client.HistoryFromJSON(f, client.HistoryJSONOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, history.Events, file)
+	return history.Events[0].GetWorkflowExecutionStartedEventAttributes().GetWorkflowType().GetName()
+}
+
+// historyName names a history by its generation and file, e.g. add-payment-processing/completed
+func historyName(file string) string {
+	return filepath.Base(filepath.Dir(file)) + "/" + strings.TrimSuffix(filepath.Base(file), ".json")
+}
+
+// newReplayer registers the workflows the way the worker does
+func newReplayer() worker.WorkflowReplayer {
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflow(workflows.OrderWorkflow)
+	replayer.RegisterWorkflow(workflows.PaymentWorkflow)
+	return replayer
+}
+
+func TestReplayHistories(t *testing.T) {
+	for _, file := range historyCorpus(t) {
+		t.Run(historyName(file), func(t *testing.T) {
+			err := newReplayer().ReplayWorkflowHistoryFromJSONFile(nil, file)
+			require.NoError(t, err, "replaying %s is nondeterministic, guard the change with workflow.GetVersion", file)
+		})
+	}
+}
+
+// reorderedOrderWorkflow processes the order before validating it, an incompatible change to OrderWorkflow
+func reorderedOrderWorkflow(ctx workflow.Context, order models.Order) error {
+	var act *activities.Activities
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+	if err := workflow.ExecuteActivity(ctx, act.ProcessOrder, order).Get(ctx, nil); err != nil {
+		return err
+	}
+	return workflow.ExecuteActivity(ctx, act.ValidateOrder, order).Get(ctx, nil)
+}
+
+func TestReplayHistories_DetectsNondeterminism(t *testing.T) {
+	for _, file := range historyCorpus(t) {
+		if workflowType(t, file) != "OrderWorkflow" {
+			continue
+		}
+		t.Run(historyName(file), func(t *testing.T) {
+			replayer := worker.NewWorkflowReplayer()
+			replayer.RegisterWorkflowWithOptions(reorderedOrderWorkflow, workflow.RegisterOptions{Name: "OrderWorkflow"})
+			replayer.RegisterWorkflow(workflows.PaymentWorkflow)
+
+			err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, file)
+			require.Error(t, err)
+			// TMPRL1100 is the code of every nondeterminism error of the SDK
+			assert.Contains(t, err.Error(), "TMPRL1100")
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"temporal-order-system/models"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// sagaRecorder is a fake compensating activity that records the order it ran in
+type sagaRecorder struct {
+	calls []string
+	fail  map[string]bool
+	// transient is the number of retryable failures of a step before it succeeds
+	transient map[string]int
+}
+
+func (r *sagaRecorder) Undo(ctx context.Context, step string) error {
+	if r.transient[step] > 0 {
+		r.transient[step]--
+		return fmt.Errorf("cannot undo %s yet", step)
+	}
+	r.calls = append(r.calls, step)
+	if r.fail[step] {
+		return temporal.NewNonRetryableApplicationError(fmt.Sprintf("cannot undo %s", step), "UndoFailed", nil)
+	}
+	return nil
+}
+
+func TestSaga_Compensate(t *testing.T) {
+	tests := []struct {
+		name         string
+		parallel     bool
+		required     bool
+		fail         map[string]bool
+		transient    map[string]int
+		wantCalls    []string
+		wantFailures []string
+	}{
+		{
+			name:      "Sequential - LIFO Order",
+			wantCalls: []string{"step-3", "step-2", "step-1"},
+		},
+		{
+			name:         "Sequential - Continues After Failure",
+			fail:         map[string]bool{"step-2": true},
+			wantCalls:    []string{"step-3", "step-2", "step-1"},
+			wantFailures: []string{"undo-step-2"},
+		},
+		{
+			name:         "Parallel - Runs All Compensations",
+			parallel:     true,
+			fail:         map[string]bool{"step-1": true, "step-3": true},
+			wantFailures: []string{"undo-step-3", "undo-step-1"},
+		},
+		{
+			name:         "Best Effort - Gives Up After Retries",
+			transient:    map[string]int{"step-2": 7},
+			wantCalls:    []string{"step-3", "step-1"},
+			wantFailures: []string{"undo-step-2"},
+		},
+		{
+			name:      "Required - Retried Until It Succeeds",
+			required:  true,
+			transient: map[string]int{"step-2": 7},
+			wantCalls: []string{"step-3", "step-2", "step-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSuite := &testsuite.WorkflowTestSuite{}
+			env := testSuite.NewTestWorkflowEnvironment()
+
+			recorder := &sagaRecorder{fail: tt.fail, transient: tt.transient}
+			env.RegisterActivity(recorder.Undo)
+
+			var saga *workflows.Saga
+			sagaWorkflow := func(ctx workflow.Context) error {
+				options := workflows.DefaultSagaOptions()
+				options.Parallel = tt.parallel
+				saga = workflows.NewSaga(options)
+				for i := 1; i <= 3; i++ {
+					step := fmt.Sprintf("step-%d", i)
+					if tt.required {
+						saga.AddRequiredCompensation("undo-"+step, recorder.Undo, step)
+					} else {
+						saga.AddCompensation("undo-"+step, recorder.Undo, step)
+					}
+				}
+				saga.Compensate(ctx)
+				return saga.Error(errors.New("step-4 failed"))
+			}
+			env.RegisterWorkflow(sagaWorkflow)
+
+			env.ExecuteWorkflow(sagaWorkflow)
+
+			require.True(t, env.IsWorkflowCompleted())
+			if tt.wantCalls != nil {
+				assert.Equal(t, tt.wantCalls, recorder.calls)
+			} else {
+				assert.ElementsMatch(t, []string{"step-1", "step-2", "step-3"}, recorder.calls)
+			}
+
+			err := env.GetWorkflowError()
+			require.Error(t, err)
+			var appErr *temporal.ApplicationError
+			if len(tt.wantFailures) == 0 {
+				assert.False(t, errors.As(err, &appErr) && appErr.Type() == workflows.ErrTypeCompensationFailed)
+				return
+			}
+
+			require.True(t, errors.As(err, &appErr))
+			assert.Equal(t, workflows.ErrTypeCompensationFailed, appErr.Type())
+			var failures []models.CompensationFailure
+			require.NoError(t, appErr.Details(&failures))
+			steps := make([]string, len(failures))
+			for i, f := range failures {
+				steps[i] = f.Step
+				assert.Contains(t, f.Error, "cannot undo")
+				assert.True(t, saga.Failed(f.Step))
+			}
+			assert.Equal(t, tt.wantFailures, steps)
+		})
+	}
+}
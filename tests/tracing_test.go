@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/models"
+	"temporal-order-system/tracing"
+	"temporal-order-system/workflows"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+)
+
+// tracerHeaderKey is the Temporal header in which Temporal's OpenTelemetry interceptor carries
+// span contexts
+const tracerHeaderKey = "_tracer-data"
+
+func TestTracingInterceptor_OrderWorkflow(t *testing.T) {
+	order := testOrder("TEST-TRACE-001")
+
+	tests := []struct {
+		name        string
+		fromStarter bool
+	}{
+		{
+			name:        "Success - Continues Starter Trace",
+			fromStarter: true,
+		},
+		{
+			name: "Success - New Trace Without Header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			var traceparent string
+			validationServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparent = r.Header.Get("traceparent")
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(models.ValidationResponse{Valid: true, Message: "Order is valid"})
+			}))
+			defer validationServer.Close()
+
+			tracingInterceptor, err := tracing.NewInterceptor(provider)
+			require.NoError(t, err)
+
+			testSuite := &testsuite.WorkflowTestSuite{}
+			env := newOrderTestEnv(testSuite)
+			env.SetWorkerOptions(worker.Options{
+				Interceptors: []interceptor.WorkerInterceptor{tracingInterceptor},
+			})
+
+			// Validation calls the server, which records the trace context it receives
+			env.OnActivity(env.act.ValidateOrder, mock.Anything, mock.Anything).Return(activities.NewActivities(validationServer.URL).ValidateOrder)
+			env.mockSuccess()
+
+			// The header the starter's client interceptor writes when starting the workflow
+			var starterSpan trace.Span
+			if tt.fromStarter {
+				_, starterSpan = provider.Tracer("starter").Start(context.Background(), "StartWorkflow:OrderWorkflow")
+				carrier := propagation.MapCarrier{}
+				tracing.Propagator.Inject(trace.ContextWithSpan(context.Background(), starterSpan), carrier)
+				payload, err := converter.GetDefaultDataConverter().ToPayload(map[string]string(carrier))
+				require.NoError(t, err)
+				env.SetHeader(&commonpb.Header{Fields: map[string]*commonpb.Payload{tracerHeaderKey: payload}})
+			}
+
+			env.ExecuteWorkflow(workflows.OrderWorkflow, order)
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			spans := map[string]sdktrace.ReadOnlySpan{}
+			for _, s := range recorder.Ended() {
+				spans[s.Name()] = s
+			}
+			for _, name := range []string{
+				"RunWorkflow:OrderWorkflow",
+				"StartActivity:ValidateOrder",
+				"RunActivity:ValidateOrder",
+				"StartChildWorkflow:PaymentWorkflow",
+				"RunWorkflow:PaymentWorkflow",
+			} {
+				require.Contains(t, spans, name)
+			}
+
+			root := spans["RunWorkflow:OrderWorkflow"]
+			traceID := root.SpanContext().TraceID()
+			if tt.fromStarter {
+				assert.Equal(t, starterSpan.SpanContext().TraceID(), traceID)
+				assert.Equal(t, starterSpan.SpanContext().SpanID(), root.Parent().SpanID())
+			} else {
+				assert.False(t, root.Parent().IsValid())
+			}
+
+			// Every step of the order, including the child workflow, is in the same trace
+			for name, s := range spans {
+				assert.Equal(t, traceID, s.SpanContext().TraceID(), name)
+			}
+			assert.Equal(t, spans["StartActivity:ValidateOrder"].SpanContext().SpanID(), spans["RunActivity:ValidateOrder"].Parent().SpanID())
+			assert.Equal(t, spans["StartChildWorkflow:PaymentWorkflow"].SpanContext().SpanID(), spans["RunWorkflow:PaymentWorkflow"].Parent().SpanID())
+
+			// The validation service is called within the span of ValidateOrder
+			validateSpan := spans["RunActivity:ValidateOrder"].SpanContext()
+			assert.Equal(t, "00-"+validateSpan.TraceID().String()+"-"+validateSpan.SpanID().String()+"-01", traceparent)
+		})
+	}
+}
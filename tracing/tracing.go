@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/sdk/contrib/opentelemetry"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// Exporters selectable with OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Propagator carries span contexts as W3C traceparent and baggage headers, both in Temporal
+// headers and in the HTTP requests of activities
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// instrumentationName names the tracer of the interceptor
+const instrumentationName = "temporal-order-system/tracing"
+
+// NewInterceptor returns Temporal's OpenTelemetry tracing interceptor with spans of provider
+// and the Propagator. Set on client.Options it traces the client and every worker created from
+// the client: starting, signalling, querying and updating workflows, running workflows,
+// activities and child workflows. Span contexts travel in the Temporal headers.
+func NewInterceptor(provider trace.TracerProvider) (interceptor.Interceptor, error) {
+	i, err := opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{
+		Tracer:            provider.Tracer(instrumentationName),
+		TextMapPropagator: Propagator,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing interceptor: %w", err)
+	}
+	return i, nil
+}
+
+// Setup installs the global tracer provider for the exporter named by OTEL_TRACES_EXPORTER and
+// the global Propagator. Without an exporter spans are not recorded, but trace contexts are
+// still propagated. The returned shutdown flushes pending spans.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	exporterName := os.Getenv("OTEL_TRACES_EXPORTER")
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		// Spans go to stderr so the output of the starter stays parseable
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		// The endpoint follows OTEL_EXPORTER_OTLP_ENDPOINT, http://localhost:4318 by default
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q: use %s, %s or %s", exporterName, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporterName, err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over serviceName
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
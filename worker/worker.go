@@ -1,16 +1,26 @@
 package main
 
 import (
-	"crypto/rand"
+	"context"
 	"encoding/hex"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"temporal-order-system/codec"
+	"time"
 
 	"temporal-order-system/activities"
+	"temporal-order-system/gateway"
+	"temporal-order-system/logging"
+	"temporal-order-system/metrics"
+	"temporal-order-system/tracing"
 	"temporal-order-system/workflows"
 
+	"go.opentelemetry.io/otel"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	sdklog "go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/worker"
 )
 
@@ -19,6 +29,14 @@ const (
 )
 
 func main() {
+	// Log through slog as configured by LOG_LEVEL and LOG_FORMAT, JSON at info by default
+	logOptions, err := logging.OptionsFromEnv(logging.Options{Level: slog.LevelInfo, Format: logging.FormatJSON})
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	logger := logging.NewSlogLogger(os.Stderr, logOptions)
+	slog.SetDefault(logger)
+
 	// Get Temporal server address from environment or use default
 	temporalAddress := os.Getenv("TEMPORAL_ADDRESS")
 	if temporalAddress == "" {
@@ -31,44 +49,71 @@ func main() {
 		wiremockURL = "http://localhost:8081"
 	}
 
-	// Get or generate encryption key
-	encryptionKey := os.Getenv("ENCRYPTION_KEY")
-	var keyBytes []byte
-	var err error
+	// Get payment gateway URL from environment; the in-memory fake is used when unset
+	paymentGatewayURL := os.Getenv("PAYMENT_GATEWAY_URL")
 
-	if encryptionKey != "" {
-		keyBytes, err = hex.DecodeString(encryptionKey)
-		if err != nil {
-			log.Fatalf("Failed to decode encryption key: %v", err)
-		}
-	} else {
-		// Generate a random 32-byte key for AES-256
-		keyBytes = make([]byte, 32)
-		if _, err := rand.Read(keyBytes); err != nil {
-			log.Fatalf("Failed to generate encryption key: %v", err)
-		}
-		log.Printf("Generated encryption key: %s", hex.EncodeToString(keyBytes))
-		log.Println("Set ENCRYPTION_KEY environment variable to use this key in production")
+	// Load the encryption setup; startup fails without keys unless ENCRYPTION_DEV_MODE is set
+	encryption, err := codec.LoadEncryptionConfig()
+	if err != nil {
+		fatal("Failed to load encryption config", err)
 	}
+	if encryption.GeneratedKey != nil {
+		slog.Warn("Dev mode: set ENCRYPTION_KEY on the starter to the generated key to share payloads",
+			"generated_key", hex.EncodeToString(encryption.GeneratedKey))
+	}
+
+	// Create data converter with compression and encryption
+	dataConverter, err := encryption.Builder().Build()
+	if err != nil {
+		fatal("Failed to create encryption data converter", err)
+	}
+
+	// Get metrics address from environment or use default
+	metricsAddress := os.Getenv("METRICS_ADDRESS")
+	if metricsAddress == "" {
+		metricsAddress = ":9464"
+	}
+
+	// Serve the SDK and business metrics for Prometheus
+	registry := metrics.NewRegistry()
+	defer registry.Close()
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", registry)
+		if err := http.ListenAndServe(metricsAddress, mux); err != nil {
+			fatal("Unable to serve metrics", err)
+		}
+	}()
 
-	// Create data converter with encryption
-	dataConverter, err := codec.NewEncryptionDataConverter(keyBytes)
+	// Export traces as configured by OTEL_TRACES_EXPORTER
+	shutdownTracing, err := tracing.Setup(context.Background(), "order-worker")
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+	tracingInterceptor, err := tracing.NewInterceptor(otel.GetTracerProvider())
 	if err != nil {
-		log.Fatalf("Failed to create encryption data converter: %v", err)
+		fatal("Failed to set up tracing", err)
 	}
 
-	// Create Temporal client with encryption
+	// Create Temporal client with encryption, metrics, tracing and the slog logger; workers
+	// created from the client inherit the tracing interceptor
 	c, err := client.Dial(client.Options{
-		HostPort:      temporalAddress,
-		DataConverter: dataConverter,
+		HostPort:       temporalAddress,
+		DataConverter:  dataConverter,
+		MetricsHandler: registry.Handler(),
+		Interceptors:   []interceptor.ClientInterceptor{tracingInterceptor},
+		Logger:         sdklog.NewStructuredLogger(logger),
 	})
 	if err != nil {
-		log.Fatalf("Unable to create Temporal client: %v", err)
+		fatal("Unable to create Temporal client", err)
 	}
 	defer c.Close()
 
-	// Create worker
-	w := worker.New(c, TaskQueueName, worker.Options{})
+	// Create worker; workflow and activity log lines carry the order and execution
+	w := worker.New(c, TaskQueueName, worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{logging.NewInterceptor()},
+	})
 
 	// Register workflows
 	w.RegisterWorkflow(workflows.OrderWorkflow)
@@ -81,22 +126,57 @@ func main() {
 	w.RegisterActivity(orderActivities.NotifyCustomer)
 	w.RegisterActivity(orderActivities.RollbackOrder)
 
-	paymentActivities := activities.NewPaymentActivities()
+	var paymentGateway gateway.PaymentGateway
+	if paymentGatewayURL != "" {
+		paymentGateway = gateway.NewHTTPGateway(paymentGatewayURL)
+	} else {
+		fakeGateway := gateway.NewFakeGateway()
+		fakeGateway.Latency = 1 * time.Second
+		paymentGateway = fakeGateway
+	}
+
+	paymentActivities := activities.NewPaymentActivities(paymentGateway)
 	w.RegisterActivity(paymentActivities.AuthorizePayment)
 	w.RegisterActivity(paymentActivities.CapturePayment)
 	w.RegisterActivity(paymentActivities.VoidAuthorization)
 	w.RegisterActivity(paymentActivities.RefundPayment)
 
-	log.Println("Starting Temporal worker...")
-	log.Printf("Temporal address: %s", temporalAddress)
-	log.Printf("Task queue: %s", TaskQueueName)
-	log.Printf("WireMock URL: %s", wiremockURL)
-	log.Println("Registered workflows: OrderWorkflow, PaymentWorkflow")
-	log.Println("Encryption: Enabled")
+	paymentGatewayName := paymentGatewayURL
+	if paymentGatewayName == "" {
+		paymentGatewayName = "in-memory fake"
+	}
+	tracesExporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	if tracesExporter == "" {
+		tracesExporter = tracing.ExporterNone
+	}
+	slog.Info("Starting Temporal worker",
+		"temporal_address", temporalAddress,
+		"task_queue", TaskQueueName,
+		"wiremock_url", wiremockURL,
+		"payment_gateway", paymentGatewayName,
+		"workflows", []string{"OrderWorkflow", "PaymentWorkflow"},
+		"metrics_url", "http://"+metricsAddress+"/metrics",
+		"traces_exporter", tracesExporter,
+	)
+	if kmsEndpoint := os.Getenv("ENCRYPTION_KMS_ENDPOINT"); kmsEndpoint != "" {
+		slog.Info("Encryption enabled", "mode", "envelope", "kms_endpoint", kmsEndpoint, "field_level", encryption.FieldLevel)
+	} else {
+		activeKeyID, _ := encryption.Keyring.ActiveKey()
+		slog.Info("Encryption enabled", "mode", "envelope", "master_key", activeKeyID, "keys", encryption.Keyring.IDs(), "field_level", encryption.FieldLevel)
+	}
+	if encryption.BlobStore != nil {
+		slog.Info("Claim check enabled", "threshold_bytes", codec.DefaultClaimCheckThreshold)
+	}
 
 	// Start worker
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
-		log.Fatalf("Unable to start worker: %v", err)
+		fatal("Unable to start worker", err)
 	}
 }
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,87 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"temporal-order-system/models"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// UpdateWaitForChange long-polls the progress of an order, see progress
+	UpdateWaitForChange = "wait-for-change"
+
+	// DefaultChangeWaitTimeout and MaxChangeWaitTimeout bound how long a wait-for-change
+	// update waits when nothing changes
+	DefaultChangeWaitTimeout = 30 * time.Second
+	MaxChangeWaitTimeout     = 5 * time.Minute
+)
+
+// ChangeWait is the argument of the wait-for-change update
+type ChangeWait struct {
+	// Version is the state version the caller has seen; -1 returns the current progress at once
+	Version int64 `json:"version"`
+	// Timeout bounds the wait, DefaultChangeWaitTimeout when zero
+	Timeout time.Duration `json:"timeout"`
+}
+
+// progress serves the wait-for-change update. An update blocks until the state version
+// differs from the caller's, the timeout elapses or the workflow closes, then returns the
+// state and the timeline. Queries cannot block, so this is how clients follow an order
+// without polling. Each call adds a few events to the history, so callers should wait for
+// long timeouts rather than poll often.
+type progress struct {
+	state    *models.WorkflowState
+	timeline *timeline
+	closing  bool
+}
+
+// setupProgress registers the wait-for-change update
+func setupProgress(ctx workflow.Context, state *models.WorkflowState, tl *timeline) (*progress, error) {
+	p := &progress{state: state, timeline: tl}
+	err := workflow.SetUpdateHandlerWithOptions(ctx, UpdateWaitForChange, p.wait, workflow.UpdateHandlerOptions{
+		Validator: func(w ChangeWait) error {
+			if w.Timeout < 0 || w.Timeout > MaxChangeWaitTimeout {
+				return fmt.Errorf("timeout must be between 0 and %s, got %s", MaxChangeWaitTimeout, w.Timeout)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set update handler %s: %w", UpdateWaitForChange, err)
+	}
+	return p, nil
+}
+
+// wait blocks until the progress moves past the caller's version
+func (p *progress) wait(ctx workflow.Context, w ChangeWait) (models.OrderProgress, error) {
+	changed := func() bool { return p.closing || p.state.Version != w.Version }
+	if !changed() {
+		timeout := w.Timeout
+		if timeout == 0 {
+			timeout = DefaultChangeWaitTimeout
+		}
+
+		// Cancel the timer once the wait is over, so it does not fire into the history later
+		timerCtx, cancel := workflow.WithCancel(ctx)
+		defer cancel()
+		if _, err := workflow.AwaitWithTimeout(timerCtx, timeout, changed); err != nil {
+			return models.OrderProgress{}, err
+		}
+	}
+
+	return models.OrderProgress{
+		State:    *p.state,
+		Timeline: p.timeline.Events(),
+		Final:    p.closing,
+	}, nil
+}
+
+// close answers every waiting update before the workflow returns, so none fails because
+// the workflow completed
+func (p *progress) close(ctx workflow.Context) {
+	p.closing = true
+	_ = workflow.Await(ctx, func() bool { return workflow.AllHandlersFinished(ctx) })
+}
@@ -0,0 +1,143 @@
+package workflows
+
+import (
+	"fmt"
+
+	"temporal-order-system/models"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// orderSignals receives the cancel and expedite signals for the whole life of an order
+// workflow. Each request takes effect at most once; repeated and late requests are
+// recorded in the timeline as ignored, in one event per signal that counts them.
+//
+// cancel:
+//   - PENDING, before validation starts: the order is cancelled without validating it
+//   - PENDING, while validating: validation finishes, then the order is rolled back and cancelled
+//   - VALIDATED, before or during payment: a running payment finishes, then the payment is
+//     refunded, the order rolled back and cancelled
+//   - PROCESSING or a terminal status: ignored, the order can no longer be cancelled
+//
+// expedite:
+//   - PENDING, before validation starts: validation and processing use the expedited activity options
+//   - PENDING or VALIDATED, once validation started: processing uses the expedited options
+//   - PROCESSING: only the completion notification reflects it
+//   - CANCELLED, FAILED or COMPLETED: ignored
+type orderSignals struct {
+	cancelChan   workflow.ReceiveChannel
+	expediteChan workflow.ReceiveChannel
+
+	state    *models.WorkflowState
+	timeline *timeline
+	amend    *amendments
+	// ignored holds the timeline event of the ignored requests of each signal
+	ignored map[string]int
+
+	cancelled bool
+	expedited bool
+}
+
+// newOrderSignals creates the signal channels of an order workflow
+func newOrderSignals(ctx workflow.Context, state *models.WorkflowState, tl *timeline, amend *amendments) *orderSignals {
+	return &orderSignals{
+		cancelChan:   workflow.GetSignalChannel(ctx, SignalCancel),
+		expediteChan: workflow.GetSignalChannel(ctx, SignalExpedite),
+		state:        state,
+		timeline:     tl,
+		amend:        amend,
+		ignored:      make(map[string]int),
+	}
+}
+
+// drain applies signals that are already buffered, such as those sent with the start request
+func (s *orderSignals) drain(ctx workflow.Context) {
+	for s.cancelChan.ReceiveAsync(nil) {
+		s.cancel(ctx)
+	}
+	for s.expediteChan.ReceiveAsync(nil) {
+		s.expedite(ctx)
+	}
+}
+
+// listen applies signals as they arrive until the workflow completes
+func (s *orderSignals) listen(ctx workflow.Context) {
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		selector := workflow.NewSelector(gCtx)
+		selector.AddReceive(s.cancelChan, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(gCtx, nil)
+			s.cancel(gCtx)
+		})
+		selector.AddReceive(s.expediteChan, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(gCtx, nil)
+			s.expedite(gCtx)
+		})
+
+		for {
+			selector.Select(gCtx)
+		}
+	})
+}
+
+// cancel records a cancel request
+func (s *orderSignals) cancel(ctx workflow.Context) {
+	logger := workflow.GetLogger(ctx)
+
+	var ignored string
+	switch {
+	case s.cancelled:
+		ignored = "order cancellation already requested"
+	case s.state.Status == models.OrderStatusProcessing || s.state.Status.IsTerminal():
+		ignored = fmt.Sprintf("order is already %s", s.state.Status)
+	}
+	if ignored != "" {
+		s.ignore(ctx, SignalCancel, ignored)
+		return
+	}
+
+	s.cancelled = true
+	s.amend.closedReason = "order was cancelled"
+	s.state.LastUpdated = workflow.Now(ctx)
+	s.timeline.record(ctx, SignalCancel, signalTrigger(SignalCancel))
+	logger.Info("Order cancelled via signal")
+}
+
+// expedite records an expedite request
+func (s *orderSignals) expedite(ctx workflow.Context) {
+	logger := workflow.GetLogger(ctx)
+
+	var ignored string
+	switch {
+	case s.expedited:
+		ignored = "order is already expedited"
+	case s.cancelled:
+		ignored = "order cancellation already requested"
+	case s.state.Status.IsTerminal():
+		ignored = fmt.Sprintf("order is already %s", s.state.Status)
+	}
+	if ignored != "" {
+		s.ignore(ctx, SignalExpedite, ignored)
+		return
+	}
+
+	s.expedited = true
+	s.state.Expedited = true
+	s.state.LastUpdated = workflow.Now(ctx)
+	s.timeline.record(ctx, SignalExpedite, signalTrigger(SignalExpedite))
+	logger.Info("Order expedited via signal")
+}
+
+// ignore records a signal that had no effect. The first ignored request of a signal adds a
+// timeline event, later ones update it, so repeated signals cannot grow the timeline.
+func (s *orderSignals) ignore(ctx workflow.Context, signal, reason string) {
+	err := fmt.Errorf("ignored: %s", reason)
+	if i, ok := s.ignored[signal]; ok {
+		s.timeline.repeat(ctx, i, err)
+	} else {
+		i = s.timeline.begin(ctx, signal, signalTrigger(signal))
+		s.timeline.events[i].Count = 1
+		s.timeline.end(ctx, i, err)
+		s.ignored[signal] = i
+	}
+	workflow.GetLogger(ctx).Info("Signal ignored", "signal", signal, "reason", reason)
+}
@@ -0,0 +1,93 @@
+package workflows
+
+import (
+	"errors"
+
+	"temporal-order-system/models"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	QueryTimeline = "timeline"
+)
+
+// timeline records the steps of an order workflow in the order they started
+type timeline struct {
+	state  *models.WorkflowState
+	events []models.TimelineEvent
+}
+
+// newTimeline creates a timeline that stamps events with the current workflow status
+func newTimeline(state *models.WorkflowState) *timeline {
+	return &timeline{state: state}
+}
+
+// Events returns a copy of the recorded events
+func (t *timeline) Events() []models.TimelineEvent {
+	return append([]models.TimelineEvent(nil), t.events...)
+}
+
+// begin records the start of a step and returns its index for end
+func (t *timeline) begin(ctx workflow.Context, step, trigger string) int {
+	t.state.Version++
+	t.events = append(t.events, models.TimelineEvent{
+		Step:      step,
+		Trigger:   trigger,
+		Status:    t.state.Status,
+		StartedAt: workflow.Now(ctx),
+	})
+	return len(t.events) - 1
+}
+
+// end records the outcome of a step. ctx must carry the activity options the step ran
+// with, so that an activity that exhausted its retries reports every attempt.
+func (t *timeline) end(ctx workflow.Context, i int, err error) {
+	now := workflow.Now(ctx)
+	t.state.Version++
+	event := &t.events[i]
+	event.EndedAt = &now
+	event.Status = t.state.Status
+	if err != nil {
+		event.Error = err.Error()
+		event.Attempts = attempts(ctx, err)
+	}
+}
+
+// repeat counts another occurrence of the ended event i, such as a signal ignored again, and
+// moves the event to the current time
+func (t *timeline) repeat(ctx workflow.Context, i int, err error) {
+	now := workflow.Now(ctx)
+	t.state.Version++
+	event := &t.events[i]
+	event.Count++
+	event.StartedAt = now
+	event.EndedAt = &now
+	event.Status = t.state.Status
+	event.Error = err.Error()
+}
+
+// record adds a step that starts and ends at once, such as a received signal
+func (t *timeline) record(ctx workflow.Context, step, trigger string) {
+	t.end(ctx, t.begin(ctx, step, trigger), nil)
+}
+
+// attempts returns how many attempts a failed activity made, or 0 when it is unknown. The
+// server retries activities without involving the workflow, so the count is only known when
+// the retry policy ran out.
+func attempts(ctx workflow.Context, err error) int {
+	var activityErr *temporal.ActivityError
+	if !errors.As(err, &activityErr) || activityErr.RetryState() != enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED {
+		return 0
+	}
+	if policy := workflow.GetActivityOptions(ctx).RetryPolicy; policy != nil && policy.MaximumAttempts > 0 {
+		return int(policy.MaximumAttempts)
+	}
+	return 0
+}
+
+// signalTrigger and updateTrigger name the trigger of a timeline event
+func signalTrigger(name string) string { return "signal:" + name }
+func updateTrigger(name string) string { return "update:" + name }
@@ -0,0 +1,199 @@
+package workflows
+
+import (
+	"fmt"
+
+	"temporal-order-system/activities"
+	"temporal-order-system/models"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	UpdateAddItem               = "add-item"
+	UpdateRemoveItem            = "remove-item"
+	UpdateChangeQuantity        = "change-quantity"
+	UpdateChangeShippingAddress = "change-shipping-address"
+	UpdateApplyDiscount         = "apply-discount"
+)
+
+// QuantityChange is the argument of the change-quantity update
+type QuantityChange struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// amendments applies order amendments received as workflow updates. Amendments are
+// accepted until the order is closed, which happens before payment or processing starts.
+type amendments struct {
+	order    *models.Order
+	state    *models.WorkflowState
+	timeline *timeline
+	mutex    workflow.Mutex
+	// activityOptions are used to validate amended orders; update handlers run on the
+	// root workflow context, which carries no activity options
+	activityOptions workflow.ActivityOptions
+
+	// closedReason is set once the order can no longer be amended
+	closedReason string
+	// validated is set once the order has been submitted for validation, after which
+	// amendments that change the amount are validated again
+	validated bool
+	inFlight  int
+}
+
+// setupAmendments registers the amendment update handlers of an order workflow
+func setupAmendments(ctx workflow.Context, order *models.Order, state *models.WorkflowState, tl *timeline, activityOptions workflow.ActivityOptions) (*amendments, error) {
+	a := &amendments{
+		order:           order,
+		state:           state,
+		timeline:        tl,
+		mutex:           workflow.NewMutex(ctx),
+		activityOptions: activityOptions,
+	}
+
+	err := registerAmendment(ctx, a, UpdateAddItem, func(o *models.Order, item models.OrderItem) error {
+		if item.ProductID == "" {
+			return fmt.Errorf("product ID is required")
+		}
+		if item.Quantity <= 0 {
+			return fmt.Errorf("quantity must be positive, got %d", item.Quantity)
+		}
+		if !item.Price.IsPositive() {
+			return fmt.Errorf("price must be positive, got %s", item.Price)
+		}
+		if o.FindItem(item.ProductID) >= 0 {
+			return fmt.Errorf("product %s is already in the order", item.ProductID)
+		}
+		o.Items = append(o.Items, item)
+		return nil
+	})
+	if err == nil {
+		err = registerAmendment(ctx, a, UpdateRemoveItem, func(o *models.Order, productID string) error {
+			i := o.FindItem(productID)
+			if i < 0 {
+				return fmt.Errorf("product %s is not in the order", productID)
+			}
+			if len(o.Items) == 1 {
+				return fmt.Errorf("cannot remove the last item; cancel the order instead")
+			}
+			o.Items = append(o.Items[:i], o.Items[i+1:]...)
+			return nil
+		})
+	}
+	if err == nil {
+		err = registerAmendment(ctx, a, UpdateChangeQuantity, func(o *models.Order, change QuantityChange) error {
+			i := o.FindItem(change.ProductID)
+			if i < 0 {
+				return fmt.Errorf("product %s is not in the order", change.ProductID)
+			}
+			if change.Quantity <= 0 {
+				return fmt.Errorf("quantity must be positive, got %d", change.Quantity)
+			}
+			o.Items[i].Quantity = change.Quantity
+			return nil
+		})
+	}
+	if err == nil {
+		err = registerAmendment(ctx, a, UpdateChangeShippingAddress, func(o *models.Order, address models.Address) error {
+			if err := address.Validate(); err != nil {
+				return err
+			}
+			o.ShippingAddress = &address
+			return nil
+		})
+	}
+	if err == nil {
+		err = registerAmendment(ctx, a, UpdateApplyDiscount, func(o *models.Order, code string) error {
+			if _, ok := models.DiscountPercent(code); !ok {
+				return fmt.Errorf("unknown discount code %q", code)
+			}
+			o.DiscountCode = code
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set update handler: %w", err)
+	}
+	return a, nil
+}
+
+// registerAmendment registers an update handler that applies change to a copy of the order,
+// with a validator that rejects the update before it is written to history
+func registerAmendment[T any](ctx workflow.Context, a *amendments, name string, change func(*models.Order, T) error) error {
+	return workflow.SetUpdateHandlerWithOptions(ctx, name,
+		func(ctx workflow.Context, arg T) (models.Order, error) {
+			return a.amend(ctx, name, func(o *models.Order) error { return change(o, arg) })
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(arg T) error {
+				if a.closedReason != "" {
+					return fmt.Errorf("order can no longer be amended: %s", a.closedReason)
+				}
+				_, err := a.candidate(func(o *models.Order) error { return change(o, arg) })
+				return err
+			},
+		},
+	)
+}
+
+// candidate returns a repriced copy of the order with change applied
+func (a *amendments) candidate(change func(*models.Order) error) (models.Order, error) {
+	amended := a.order.Clone()
+	if err := change(&amended); err != nil {
+		return models.Order{}, err
+	}
+	if err := amended.Reprice(); err != nil {
+		return models.Order{}, err
+	}
+	if !amended.Amount.IsPositive() {
+		return models.Order{}, fmt.Errorf("order amount must be positive, got %s", amended.Amount)
+	}
+	return amended, nil
+}
+
+// amend applies an accepted amendment, validating the order again when its amount changed
+func (a *amendments) amend(ctx workflow.Context, name string, change func(*models.Order) error) (_ models.Order, err error) {
+	a.inFlight++
+	defer func() { a.inFlight-- }()
+
+	step := a.timeline.begin(ctx, name, updateTrigger(name))
+	defer func() { a.timeline.end(ctx, step, err) }()
+
+	// Amendments are applied one at a time so each one sees the previous result
+	if err := a.mutex.Lock(ctx); err != nil {
+		return models.Order{}, err
+	}
+	defer a.mutex.Unlock()
+
+	amended, err := a.candidate(change)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if a.validated && !amended.Amount.Equal(a.order.Amount) {
+		act := &activities.Activities{}
+		validateCtx := workflow.WithActivityOptions(ctx, a.activityOptions)
+		validation := a.timeline.begin(ctx, "ValidateOrder", updateTrigger(name))
+		err := workflow.ExecuteActivity(validateCtx, act.ValidateOrder, amended).Get(ctx, nil)
+		a.timeline.end(validateCtx, validation, err)
+		if err != nil {
+			return models.Order{}, fmt.Errorf("amended order failed validation: %w", err)
+		}
+	}
+
+	amended.UpdatedAt = workflow.Now(ctx)
+	*a.order = amended
+	a.state.Amount = amended.Amount
+	a.state.LastUpdated = workflow.Now(ctx)
+	workflow.GetLogger(ctx).Info("Order amended", "amount", amended.Amount)
+	return amended, nil
+}
+
+// close rejects further amendments and waits for accepted ones to finish
+func (a *amendments) close(ctx workflow.Context, reason string) error {
+	if a.closedReason == "" {
+		a.closedReason = reason
+	}
+	return workflow.Await(ctx, func() bool { return a.inFlight == 0 })
+}
@@ -2,9 +2,11 @@ package workflows
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"temporal-order-system/activities"
+	"temporal-order-system/metrics"
 	"temporal-order-system/models"
 
 	"go.temporal.io/sdk/temporal"
@@ -17,22 +19,41 @@ const (
 	QueryState     = "state"
 )
 
+// OrderWorkflowID is the workflow ID of an order, so each order has at most one running workflow
+func OrderWorkflowID(orderID string) string {
+	return fmt.Sprintf("order-workflow-%s", orderID)
+}
+
+// PaymentWorkflowID is the workflow ID of the payment child workflow of an order
+func PaymentWorkflowID(orderID string) string {
+	return fmt.Sprintf("payment-%s", orderID)
+}
+
+// OrderIDFromWorkflowID returns the order ID in an order or payment workflow ID, or "" for
+// other workflows
+func OrderIDFromWorkflowID(workflowID string) string {
+	for _, prefix := range []string{OrderWorkflowID(""), PaymentWorkflowID("")} {
+		if orderID, ok := strings.CutPrefix(workflowID, prefix); ok {
+			return orderID
+		}
+	}
+	return ""
+}
+
 // OrderWorkflow is the main workflow for processing orders
 func OrderWorkflow(ctx workflow.Context, order models.Order) error {
 	logger := workflow.GetLogger(ctx)
-	logger.Info("OrderWorkflow started", "order_id", order.ID)
+	logger.Info("OrderWorkflow started")
+	countOrder(ctx, metrics.OrdersStarted, "")
 
 	// Initialize workflow state
 	state := models.WorkflowState{
 		OrderID:     order.ID,
 		Status:      models.OrderStatusPending,
+		Amount:      order.Amount,
 		LastUpdated: workflow.Now(ctx),
 	}
 
-	// Setup signal channels
-	cancelChan := workflow.GetSignalChannel(ctx, SignalCancel)
-	expediteChan := workflow.GetSignalChannel(ctx, SignalExpedite)
-
 	// Setup query handler for workflow state
 	err := workflow.SetQueryHandler(ctx, QueryState, func() (models.WorkflowState, error) {
 		return state, nil
@@ -41,9 +62,30 @@ func OrderWorkflow(ctx workflow.Context, order models.Order) error {
 		return fmt.Errorf("failed to set query handler: %w", err)
 	}
 
+	// Setup query handler for the step timeline
+	tl := newTimeline(&state)
+	err = workflow.SetQueryHandler(ctx, QueryTimeline, func() ([]models.TimelineEvent, error) {
+		return tl.Events(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set query handler: %w", err)
+	}
+
+	// Setup the update clients long-poll to follow the order; waiting updates are answered
+	// before the workflow returns
+	prog, err := setupProgress(ctx, &state, tl)
+	if err != nil {
+		return err
+	}
+	defer prog.close(ctx)
+
 	// Version handling for backward compatibility
 	v := workflow.GetVersion(ctx, "add-payment-processing", workflow.DefaultVersion, 1)
 
+	// Version 1 decodes the PaymentResult of the payment child workflow and refunds the
+	// captured payment when a later step fails
+	refunds := workflow.GetVersion(ctx, "refund-captured-payments", workflow.DefaultVersion, 1)
+
 	// Activity options with retry policy
 	activityOptions := workflow.ActivityOptions{
 		StartToCloseTimeout: 30 * time.Second,
@@ -57,52 +99,75 @@ func OrderWorkflow(ctx workflow.Context, order models.Order) error {
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
-	// Create activities instance for method references
+	// Setup update handlers for order amendments
+	amend, err := setupAmendments(ctx, &order, &state, tl, activityOptions)
+	if err != nil {
+		return err
+	}
+
+	// Create activities instances for method references
 	act := &activities.Activities{}
+	paymentAct := &activities.PaymentActivities{}
+
+	// Compensations registered by completed steps, run in reverse order on failure or cancel
+	saga := NewSaga(DefaultSagaOptions())
 
-	// Signal state flags
-	cancelled := false
-	expedited := false
-
-	// Start async signal handler goroutine
-	workflow.Go(ctx, func(gCtx workflow.Context) {
-		selector := workflow.NewSelector(gCtx)
-
-		for {
-			selector.AddReceive(cancelChan, func(c workflow.ReceiveChannel, more bool) {
-				var signal string
-				c.Receive(gCtx, &signal)
-				cancelled = true
-				state.Status = models.OrderStatusCancelled
-				state.LastUpdated = workflow.Now(gCtx)
-				logger.Info("Order cancelled via signal", "order_id", order.ID)
-			})
-
-			selector.AddReceive(expediteChan, func(c workflow.ReceiveChannel, more bool) {
-				var signal string
-				c.Receive(gCtx, &signal)
-				expedited = true
-				state.Status = models.OrderStatusExpedited
-				state.LastUpdated = workflow.Now(gCtx)
-				logger.Info("Order expedited via signal", "order_id", order.ID)
-			})
-
-			selector.Select(gCtx)
-
-			// Exit loop if cancelled
-			if cancelled || expedited {
-				break
-			}
+	// Cancel and expedite are received for the whole workflow, see orderSignals for their rules
+	signals := newOrderSignals(ctx, &state, tl, amend)
+	signals.listen(ctx)
+
+	compensate := func(cause error) error {
+		trigger := ""
+		if signals.cancelled {
+			trigger = signalTrigger(SignalCancel)
 		}
-	})
+		step := tl.begin(ctx, "Compensate", trigger)
+		state.FailedCompensations = saga.Compensate(ctx)
+		state.Refunded = refunds >= 1 && state.PaymentDone && !saga.Failed("RefundPayment")
+		state.LastUpdated = workflow.Now(ctx)
+
+		var failed error
+		if len(state.FailedCompensations) > 0 {
+			failed = fmt.Errorf("%d compensations failed", len(state.FailedCompensations))
+		}
+		tl.end(ctx, step, failed)
+		return saga.Error(cause)
+	}
+
+	// setStatus moves the order to a new status, rejecting illegal transitions
+	setStatus := func(to models.OrderStatus) error {
+		if err := state.SetStatus(to, workflow.Now(ctx)); err != nil {
+			logger.Error("Rejected order status change", "error", err)
+			return err
+		}
+		return nil
+	}
+
+	// notify sends a customer notification; a failed notification never fails the order
+	notify := func(ctx workflow.Context, message string) {
+		step := tl.begin(ctx, "NotifyCustomer", "")
+		err := workflow.ExecuteActivity(ctx, act.NotifyCustomer, order, message).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to notify customer", "error", err)
+		}
+		tl.end(ctx, step, err)
+	}
 
 	// Step 1: Validate Order
-	logger.Info("Starting order validation", "order_id", order.ID)
-	state.Status = models.OrderStatusPending
-	state.LastUpdated = workflow.Now(ctx)
+	// Signals sent with the start request decide how validation runs
+	signals.drain(ctx)
+	if signals.cancelled {
+		logger.Info("Order cancelled before validation")
+		_ = amend.close(ctx, "order was cancelled")
+		_ = setStatus(models.OrderStatusCancelled)
+		countOrder(ctx, metrics.OrdersCancelled, "received")
+		return compensate(fmt.Errorf("order cancelled by user"))
+	}
+
+	logger.Info("Starting order validation")
 
 	validateCtx := ctx
-	if expedited {
+	if signals.expedited {
 		// Reduce timeout for expedited orders
 		validateCtx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 			StartToCloseTimeout: 15 * time.Second,
@@ -116,121 +181,160 @@ func OrderWorkflow(ctx workflow.Context, order models.Order) error {
 		})
 	}
 
+	amend.validated = true
+	step := tl.begin(ctx, "ValidateOrder", "")
 	err = workflow.ExecuteActivity(validateCtx, act.ValidateOrder, order).Get(ctx, nil)
 	if err != nil {
-		logger.Error("Order validation failed", "order_id", order.ID, "error", err)
-		state.Status = models.OrderStatusFailed
-		state.LastUpdated = workflow.Now(ctx)
+		logger.Error("Order validation failed", "error", err)
+		_ = setStatus(models.OrderStatusFailed)
+		tl.end(validateCtx, step, err)
+		_ = amend.close(ctx, "order validation failed")
+
+		countOrder(ctx, metrics.OrdersFailed, "validation")
 
 		// Send notification
-		_ = workflow.ExecuteActivity(ctx, act.NotifyCustomer, order, "Order validation failed").Get(ctx, nil)
+		notify(ctx, "Order validation failed")
 
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
 	state.ValidationDone = true
-	state.Status = models.OrderStatusValidated
-	state.LastUpdated = workflow.Now(ctx)
-	logger.Info("Order validated successfully", "order_id", order.ID)
+	if err := setStatus(models.OrderStatusValidated); err != nil {
+		return err
+	}
+	tl.end(validateCtx, step, nil)
+	logger.Info("Order validated successfully")
+
+	// Validated orders must be rolled back if any later step fails
+	saga.AddCompensation("RollbackOrder", act.RollbackOrder, order)
+
+	// Amendments accepted while validating are applied before the order moves on
+	stage := "payment has started"
+	if v == workflow.DefaultVersion {
+		stage = "processing has started"
+	}
+	if err := amend.close(ctx, stage); err != nil {
+		return err
+	}
 
 	// Check if cancelled
-	if cancelled {
-		logger.Info("Order processing cancelled after validation", "order_id", order.ID)
-		rollbackCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-			StartToCloseTimeout: 10 * time.Second,
-		})
-		_ = workflow.ExecuteActivity(rollbackCtx, act.RollbackOrder, order).Get(ctx, nil)
-		return fmt.Errorf("order cancelled by user")
+	if signals.cancelled {
+		logger.Info("Order processing cancelled after validation")
+		_ = setStatus(models.OrderStatusCancelled)
+		countOrder(ctx, metrics.OrdersCancelled, "validation")
+		return compensate(fmt.Errorf("order cancelled by user"))
 	}
 
 	// Version 1: Add payment processing
 	if v >= 1 {
 		// Step 2: Process Payment (Child Workflow)
-		logger.Info("Starting payment processing", "order_id", order.ID)
+		logger.Info("Starting payment processing")
 
+		// No execution timeout: voiding the authorization of a failed capture is retried
+		// until it succeeds
 		childWorkflowOptions := workflow.ChildWorkflowOptions{
-			WorkflowID:               fmt.Sprintf("payment-%s", order.ID),
-			WorkflowExecutionTimeout: 2 * time.Minute,
+			WorkflowID: PaymentWorkflowID(order.ID),
 		}
 		childCtx := workflow.WithChildOptions(ctx, childWorkflowOptions)
 
-		var paymentResult string
-		err = workflow.ExecuteChildWorkflow(childCtx, PaymentWorkflow, order).Get(ctx, &paymentResult)
+		var paymentResult models.PaymentResult
+		var result interface{} = &paymentResult
+		if refunds == workflow.DefaultVersion {
+			// Children of these orders may have returned a description of the payment
+			// instead of a PaymentResult; it is only logged
+			var description interface{}
+			result = &description
+		}
+		step = tl.begin(ctx, "PaymentWorkflow", "")
+		err = workflow.ExecuteChildWorkflow(childCtx, PaymentWorkflow, order).Get(ctx, result)
 		if err != nil {
-			logger.Error("Payment processing failed", "order_id", order.ID, "error", err)
-			state.Status = models.OrderStatusFailed
-			state.LastUpdated = workflow.Now(ctx)
+			logger.Error("Payment processing failed", "error", err)
+			_ = setStatus(models.OrderStatusFailed)
+			tl.end(ctx, step, err)
 
-			// Rollback
-			_ = workflow.ExecuteActivity(ctx, act.RollbackOrder, order).Get(ctx, nil)
-			_ = workflow.ExecuteActivity(ctx, act.NotifyCustomer, order, "Payment processing failed").Get(ctx, nil)
+			// Rollback (PaymentWorkflow voids its own authorization on failure)
+			countOrder(ctx, metrics.OrdersFailed, "payment")
+			err = compensate(fmt.Errorf("payment failed: %w", err))
+			notify(ctx, "Payment processing failed")
 
-			return fmt.Errorf("payment failed: %w", err)
+			return err
 		}
 
 		state.PaymentDone = true
+		state.TransactionID = paymentResult.TransactionID
 		state.LastUpdated = workflow.Now(ctx)
-		logger.Info("Payment processed successfully", "order_id", order.ID, "result", paymentResult)
+		tl.end(ctx, step, nil)
+		logger.Info("Payment processed successfully", "transaction_id", paymentResult.TransactionID)
+
+		// Captured payments must be refunded if any later step fails
+		if refunds >= 1 {
+			saga.AddRequiredCompensation("RefundPayment", paymentAct.RefundPayment, paymentResult.TransactionID, paymentResult.Amount)
+		}
 	}
 
 	// Check if cancelled
-	if cancelled {
-		logger.Info("Order processing cancelled after payment", "order_id", order.ID)
-		rollbackCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-			StartToCloseTimeout: 10 * time.Second,
-		})
-		_ = workflow.ExecuteActivity(rollbackCtx, act.RollbackOrder, order).Get(ctx, nil)
-		return fmt.Errorf("order cancelled by user")
+	if signals.cancelled {
+		logger.Info("Order processing cancelled after payment")
+		_ = setStatus(models.OrderStatusCancelled)
+		countOrder(ctx, metrics.OrdersCancelled, "payment")
+		return compensate(fmt.Errorf("order cancelled by user"))
 	}
 
 	// Step 3: Process Order
-	logger.Info("Starting order processing", "order_id", order.ID)
-	state.Status = models.OrderStatusProcessing
-	state.LastUpdated = workflow.Now(ctx)
+	logger.Info("Starting order processing")
+	if err := setStatus(models.OrderStatusProcessing); err != nil {
+		return err
+	}
 
 	processCtx := ctx
-	if expedited {
+	if signals.expedited {
 		processCtx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 			StartToCloseTimeout: 15 * time.Second,
 			HeartbeatTimeout:    3 * time.Second,
 		})
 	}
 
+	step = tl.begin(ctx, "ProcessOrder", "")
 	err = workflow.ExecuteActivity(processCtx, act.ProcessOrder, order).Get(ctx, nil)
 	if err != nil {
-		logger.Error("Order processing failed", "order_id", order.ID, "error", err)
-		state.Status = models.OrderStatusFailed
-		state.LastUpdated = workflow.Now(ctx)
+		logger.Error("Order processing failed", "error", err)
+		_ = setStatus(models.OrderStatusFailed)
+		tl.end(processCtx, step, err)
 
-		// Rollback
-		_ = workflow.ExecuteActivity(ctx, act.RollbackOrder, order).Get(ctx, nil)
-		_ = workflow.ExecuteActivity(ctx, act.NotifyCustomer, order, "Order processing failed").Get(ctx, nil)
+		// Refund and rollback
+		countOrder(ctx, metrics.OrdersFailed, "processing")
+		err = compensate(fmt.Errorf("processing failed: %w", err))
+		notify(ctx, "Order processing failed")
 
-		return fmt.Errorf("processing failed: %w", err)
+		return err
 	}
 
 	state.ProcessingDone = true
-	state.Status = models.OrderStatusCompleted
-	state.LastUpdated = workflow.Now(ctx)
-
-	// Check if cancelled (though at this point order is already processed)
-	if cancelled {
-		logger.Info("Order cancellation received but order already completed", "order_id", order.ID)
+	if err := setStatus(models.OrderStatusCompleted); err != nil {
+		return err
 	}
+	tl.end(processCtx, step, nil)
+	countOrder(ctx, metrics.OrdersCompleted, "")
 
 	// Step 4: Notify Customer
 	notificationMessage := "Your order has been processed successfully"
-	if expedited {
-		state.Status = models.OrderStatusExpedited
+	if signals.expedited {
 		notificationMessage = "Your expedited order has been processed successfully"
 	}
 
-	err = workflow.ExecuteActivity(ctx, act.NotifyCustomer, order, notificationMessage).Get(ctx, nil)
-	if err != nil {
-		logger.Warn("Failed to notify customer", "order_id", order.ID, "error", err)
-		// Don't fail the workflow if notification fails
-	}
+	// Don't fail the workflow if notification fails
+	notify(ctx, notificationMessage)
 
-	logger.Info("OrderWorkflow completed successfully", "order_id", order.ID, "expedited", expedited)
+	logger.Info("OrderWorkflow completed successfully", "expedited", signals.expedited)
 	return nil
 }
+
+// countOrder counts an order in a business metric, tagged with reason unless it is empty.
+// The metrics handler of a workflow drops metrics while replaying.
+func countOrder(ctx workflow.Context, name, reason string) {
+	handler := workflow.GetMetricsHandler(ctx)
+	if reason != "" {
+		handler = handler.WithTags(map[string]string{metrics.TagReason: reason})
+	}
+	handler.Counter(name).Inc(1)
+}
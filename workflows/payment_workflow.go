@@ -16,9 +16,9 @@ const (
 )
 
 // PaymentWorkflow is a child workflow that handles payment processing
-func PaymentWorkflow(ctx workflow.Context, order models.Order) (string, error) {
+func PaymentWorkflow(ctx workflow.Context, order models.Order) (models.PaymentResult, error) {
 	logger := workflow.GetLogger(ctx)
-	logger.Info("PaymentWorkflow started", "order_id", order.ID, "amount", order.Amount)
+	logger.Info("PaymentWorkflow started", "amount", order.Amount)
 
 	// Activity options for payment activities
 	activityOptions := workflow.ActivityOptions{
@@ -36,35 +36,41 @@ func PaymentWorkflow(ctx workflow.Context, order models.Order) (string, error) {
 	// Create payment activities instance
 	paymentAct := activities.PaymentActivities{}
 
+	// Compensations registered by completed payment steps
+	saga := NewSaga(DefaultSagaOptions())
+
 	// Step 1: Authorize Payment
-	logger.Info("Authorizing payment", "order_id", order.ID)
+	logger.Info("Authorizing payment")
 	var authorizationID string
 	err := workflow.ExecuteActivity(ctx, paymentAct.AuthorizePayment, order).Get(ctx, &authorizationID)
 	if err != nil {
-		logger.Error("Payment authorization failed", "order_id", order.ID, "error", err)
-		return "", fmt.Errorf("payment authorization failed: %w", err)
+		logger.Error("Payment authorization failed", "error", err)
+		return models.PaymentResult{}, fmt.Errorf("payment authorization failed: %w", err)
 	}
 
-	logger.Info("Payment authorized", "order_id", order.ID, "authorization_id", authorizationID)
+	logger.Info("Payment authorized", "authorization_id", authorizationID)
+
+	// An authorization that is never captured must be voided
+	saga.AddRequiredCompensation("VoidAuthorization", paymentAct.VoidAuthorization, authorizationID)
 
 	// Step 2: Capture Payment
-	logger.Info("Capturing payment", "order_id", order.ID)
+	logger.Info("Capturing payment")
 	var transactionID string
 	err = workflow.ExecuteActivity(ctx, paymentAct.CapturePayment, order, authorizationID).Get(ctx, &transactionID)
 	if err != nil {
-		logger.Error("Payment capture failed", "order_id", order.ID, "error", err)
+		logger.Error("Payment capture failed", "error", err)
 
 		// Attempt to void the authorization
-		voidCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-			StartToCloseTimeout: 10 * time.Second,
-		})
-		_ = workflow.ExecuteActivity(voidCtx, paymentAct.VoidAuthorization, authorizationID).Get(ctx, nil)
+		saga.Compensate(ctx)
 
-		return "", fmt.Errorf("payment capture failed: %w", err)
+		return models.PaymentResult{}, saga.Error(fmt.Errorf("payment capture failed: %w", err))
 	}
 
-	logger.Info("Payment captured successfully", "order_id", order.ID, "transaction_id", transactionID)
+	logger.Info("Payment captured successfully", "transaction_id", transactionID)
 
-	result := fmt.Sprintf("Payment processed successfully. Transaction ID: %s", transactionID)
-	return result, nil
+	return models.PaymentResult{
+		AuthorizationID: authorizationID,
+		TransactionID:   transactionID,
+		Amount:          order.Amount,
+	}, nil
 }
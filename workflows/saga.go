@@ -0,0 +1,186 @@
+package workflows
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"temporal-order-system/metrics"
+	"temporal-order-system/models"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// ErrTypeCompensationFailed is the application error type returned when a saga could not be fully unwound
+	ErrTypeCompensationFailed = "CompensationFailed"
+)
+
+// SagaOptions configures how a Saga runs its compensations
+type SagaOptions struct {
+	// ActivityOptions are applied to best-effort compensations, which give up after a few attempts
+	ActivityOptions workflow.ActivityOptions
+	// RequiredActivityOptions are applied to compensations that must not give up, such as
+	// refunding or voiding a payment
+	RequiredActivityOptions workflow.ActivityOptions
+	// Parallel starts all compensations at once instead of one by one in LIFO order
+	Parallel bool
+}
+
+// DefaultSagaOptions returns the compensation options used by the order workflows. Required
+// compensations are retried without limit until they succeed or fail with a non-retryable error.
+func DefaultSagaOptions() SagaOptions {
+	return SagaOptions{
+		ActivityOptions: workflow.ActivityOptions{
+			StartToCloseTimeout: 10 * time.Second,
+			RetryPolicy: &temporal.RetryPolicy{
+				InitialInterval:    1 * time.Second,
+				BackoffCoefficient: 2.0,
+				MaximumInterval:    30 * time.Second,
+				MaximumAttempts:    5,
+			},
+		},
+		RequiredActivityOptions: workflow.ActivityOptions{
+			StartToCloseTimeout: 30 * time.Second,
+			RetryPolicy: &temporal.RetryPolicy{
+				InitialInterval:    1 * time.Second,
+				BackoffCoefficient: 2.0,
+				MaximumInterval:    5 * time.Minute,
+				MaximumAttempts:    0,
+			},
+		},
+	}
+}
+
+// compensation is a single registered compensating activity
+type compensation struct {
+	step     string
+	required bool
+	activity interface{}
+	args     []interface{}
+}
+
+// Saga records the compensating activity of every completed step so that
+// a failed or cancelled workflow can be unwound in reverse order
+type Saga struct {
+	options       SagaOptions
+	compensations []compensation
+	failures      []models.CompensationFailure
+}
+
+// NewSaga creates an empty Saga with the given options
+func NewSaga(options SagaOptions) *Saga {
+	return &Saga{options: options}
+}
+
+// AddCompensation registers the best-effort activity that undoes the step that just completed.
+// step names the compensation in failures and in Failed.
+func (s *Saga) AddCompensation(step string, activity interface{}, args ...interface{}) {
+	s.compensations = append(s.compensations, compensation{
+		step:     step,
+		activity: activity,
+		args:     args,
+	})
+}
+
+// AddRequiredCompensation registers an activity that undoes the step that just completed and
+// is retried with RequiredActivityOptions until it succeeds
+func (s *Saga) AddRequiredCompensation(step string, activity interface{}, args ...interface{}) {
+	s.compensations = append(s.compensations, compensation{
+		step:     step,
+		required: true,
+		activity: activity,
+		args:     args,
+	})
+}
+
+// Compensate runs all registered compensations, newest first, and returns the ones that failed.
+// Compensations are consumed, so calling Compensate again only runs steps added since.
+func (s *Saga) Compensate(ctx workflow.Context) []models.CompensationFailure {
+	logger := workflow.GetLogger(ctx)
+
+	// Compensations must run even if the workflow itself is being cancelled
+	compCtx, _ := workflow.NewDisconnectedContext(ctx)
+	bestEffortCtx := workflow.WithActivityOptions(compCtx, s.options.ActivityOptions)
+	requiredCtx := workflow.WithActivityOptions(compCtx, s.options.RequiredActivityOptions)
+
+	pending := s.compensations
+	s.compensations = nil
+
+	futures := make([]workflow.Future, len(pending))
+	for i := len(pending) - 1; i >= 0; i-- {
+		activityCtx := bestEffortCtx
+		if pending[i].required {
+			activityCtx = requiredCtx
+		}
+		futures[i] = workflow.ExecuteActivity(activityCtx, pending[i].activity, pending[i].args...)
+		if !s.options.Parallel {
+			s.collect(compCtx, pending[i], futures[i])
+		}
+	}
+
+	if s.options.Parallel {
+		for i := len(pending) - 1; i >= 0; i-- {
+			s.collect(compCtx, pending[i], futures[i])
+		}
+	}
+
+	if len(s.failures) > 0 {
+		logger.Error("Saga compensation incomplete", "failed_compensations", len(s.failures))
+	}
+	if len(pending) > 0 {
+		outcome := metrics.OutcomeCompleted
+		if len(s.failures) > 0 {
+			outcome = metrics.OutcomeIncomplete
+		}
+		workflow.GetMetricsHandler(ctx).WithTags(map[string]string{metrics.TagOutcome: outcome}).Counter(metrics.CompensationRuns).Inc(1)
+	}
+	return s.failures
+}
+
+// collect waits for a compensation and records it if it failed
+func (s *Saga) collect(ctx workflow.Context, c compensation, future workflow.Future) {
+	logger := workflow.GetLogger(ctx)
+
+	if err := future.Get(ctx, nil); err != nil {
+		logger.Error("Compensation failed", "step", c.step, "error", err)
+		workflow.GetMetricsHandler(ctx).WithTags(map[string]string{metrics.TagStep: c.step}).Counter(metrics.CompensationFailures).Inc(1)
+		s.failures = append(s.failures, models.CompensationFailure{
+			Step:  c.step,
+			Error: err.Error(),
+		})
+		return
+	}
+	logger.Info("Compensation completed", "step", c.step)
+}
+
+// Failures returns every compensation that has failed so far
+func (s *Saga) Failures() []models.CompensationFailure {
+	return s.failures
+}
+
+// Failed reports whether the compensation registered as step failed
+func (s *Saga) Failed(step string) bool {
+	for _, f := range s.failures {
+		if f.Step == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Error annotates cause with the compensations that failed, if any.
+// The failures are attached as error details so callers can decode them.
+func (s *Saga) Error(cause error) error {
+	if len(s.failures) == 0 {
+		return cause
+	}
+
+	steps := make([]string, len(s.failures))
+	for i, f := range s.failures {
+		steps[i] = f.Step
+	}
+	message := fmt.Sprintf("%v (compensations failed: %s)", cause, strings.Join(steps, ", "))
+	return temporal.NewApplicationErrorWithCause(message, ErrTypeCompensationFailed, cause, s.failures)
+}